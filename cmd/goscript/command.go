@@ -15,7 +15,7 @@ import (
 	"github.com/google/subcommands"
 )
 
-func newLogger(verbose bool) *log.Logger {
+func newLogger(verbose bool) log.Logger {
 	level := func() log.Level {
 		if verbose {
 			return log.Debug
@@ -26,9 +26,10 @@ func newLogger(verbose bool) *log.Logger {
 }
 
 type parse struct {
+	errorReporter
 	verbose bool
 	quiet   bool
-	logger  *log.Logger
+	logger  log.Logger
 }
 
 func (*parse) Name() string {
@@ -44,6 +45,7 @@ func (*parse) Usage() string {
 }
 
 func (s *parse) SetFlags(fs *flag.FlagSet) {
+	s.setFlags(fs)
 	fs.BoolVar(&s.verbose, "v", false, "verbose")
 	fs.BoolVar(&s.quiet, "q", false, "quiet")
 }
@@ -86,19 +88,21 @@ func (s *parse) execute() error {
 
 func (s *parse) Execute(_ context.Context, _ *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
 	s.logger = newLogger(s.verbose)
+	if err := s.validate(); err != nil {
+		s.logger.Info("%v", err)
+		return subcommands.ExitUsageError
+	}
 	elapsed := util.Elapsed()
 	err := s.execute()
-	s.logger.Info("elapsed: %v err: %v", elapsed(), err)
-	if err != nil {
-		return subcommands.ExitFailure
-	}
-	return subcommands.ExitSuccess
+	return s.report(s.logger, elapsed(), err)
 }
 
 type funcPipe struct {
+	errorReporter
 	importSpecs string
 	verbose     bool
-	logger      *log.Logger
+	decompress  bool
+	logger      log.Logger
 }
 
 func (*funcPipe) Name() string {
@@ -116,8 +120,10 @@ output: go file with main package, func main()
 }
 
 func (s *funcPipe) SetFlags(fs *flag.FlagSet) {
+	s.setFlags(fs)
 	fs.BoolVar(&s.verbose, "v", false, "verbose")
 	fs.StringVar(&s.importSpecs, "i", "", "packages separeted by space")
+	fs.BoolVar(&s.decompress, "z", false, "generated program decompresses gzip, bzip2 or zstd stdin")
 }
 
 func (s *funcPipe) execute() error {
@@ -133,7 +139,7 @@ func (s *funcPipe) execute() error {
 	if len(s.importSpecs) > 0 {
 		iSpecs = strings.Split(s.importSpecs, " ")
 	}
-	r, err := translateFilePipeFromFuncLit(f, iSpecs)
+	r, err := translateFilePipeFromFuncLit(f, iSpecs, s.decompress)
 	if err != nil {
 		return err
 	}
@@ -142,19 +148,21 @@ func (s *funcPipe) execute() error {
 
 func (s *funcPipe) Execute(_ context.Context, _ *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
 	s.logger = newLogger(s.verbose)
+	if err := s.validate(); err != nil {
+		s.logger.Info("%v", err)
+		return subcommands.ExitUsageError
+	}
 	elapsed := util.Elapsed()
 	err := s.execute()
-	s.logger.Info("elapsed: %v err: %v", elapsed(), err)
-	if err != nil {
-		return subcommands.ExitFailure
-	}
-	return subcommands.ExitSuccess
+	return s.report(s.logger, elapsed(), err)
 }
 
 type mainPipe struct {
-	mainProc string
-	verbose  bool
-	logger   *log.Logger
+	errorReporter
+	mainProc   string
+	verbose    bool
+	decompress bool
+	logger     log.Logger
 }
 
 func (*mainPipe) Name() string {
@@ -173,8 +181,10 @@ output: go file with func main()
 }
 
 func (s *mainPipe) SetFlags(fs *flag.FlagSet) {
+	s.setFlags(fs)
 	fs.BoolVar(&s.verbose, "v", false, "verbose")
 	fs.StringVar(&s.mainProc, "m", "Main", "function name of main procedure")
+	fs.BoolVar(&s.decompress, "z", false, "generated program decompresses gzip, bzip2 or zstd stdin")
 }
 
 func (s *mainPipe) execute() error {
@@ -187,7 +197,7 @@ func (s *mainPipe) execute() error {
 	if err != nil {
 		return err
 	}
-	r, err := translateMainPipe(f, s.mainProc)
+	r, err := translateMainPipe(f, s.mainProc, s.decompress)
 	if err != nil {
 		return err
 	}
@@ -196,11 +206,11 @@ func (s *mainPipe) execute() error {
 
 func (s *mainPipe) Execute(_ context.Context, _ *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
 	s.logger = newLogger(s.verbose)
+	if err := s.validate(); err != nil {
+		s.logger.Info("%v", err)
+		return subcommands.ExitUsageError
+	}
 	elapsed := util.Elapsed()
 	err := s.execute()
-	s.logger.Info("elapsed: %v err: %v", elapsed(), err)
-	if err != nil {
-		return subcommands.ExitFailure
-	}
-	return subcommands.ExitSuccess
+	return s.report(s.logger, elapsed(), err)
 }
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/scanner"
+	"io"
+	"os"
+	"time"
+	"tools/pkg/errors"
+	"tools/pkg/log"
+
+	"github.com/google/subcommands"
+)
+
+const (
+	errorFormatText = "text"
+	errorFormatJSON = "json"
+)
+
+// exitStatuses maps error code into exit status, see README.md
+var exitStatuses = map[errors.Code]subcommands.ExitStatus{
+	errors.Unknown:    subcommands.ExitFailure,
+	errors.Normal:     subcommands.ExitSuccess,
+	errors.System:     3,
+	errors.Parse:      4,
+	errors.Translate:  5,
+	errors.IO:         6,
+	errors.Validate:   7,
+	errors.Iterator:   8,
+	errors.Conversion: 9,
+	errors.Fold:       10,
+	errors.Map:        11,
+	errors.Filter:     12,
+	errors.Consume:    13,
+	errors.Sort:       14,
+	errors.Lift:       15,
+	errors.Flat:       16,
+	errors.Throttle:   17,
+	errors.Sample:     18,
+	errors.Peek:       19,
+}
+
+// exitStatus returns exit status by code of err
+func exitStatus(err error) subcommands.ExitStatus {
+	if x, ok := exitStatuses[errors.CodeOf(err)]; ok {
+		return x
+	}
+	return subcommands.ExitFailure
+}
+
+type (
+	// diagnostic is machine-readable error
+	diagnostic struct {
+		Code      string      `json:"code"`
+		Exit      int         `json:"exit"`
+		Message   string      `json:"message"`
+		Positions []*position `json:"positions,omitempty"`
+	}
+
+	position struct {
+		Line    int    `json:"line"`
+		Column  int    `json:"column"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+func newDiagnostic(err error) *diagnostic {
+	d := &diagnostic{
+		Code:    errors.CodeOf(err).String(),
+		Exit:    int(exitStatus(err)),
+		Message: err.Error(),
+	}
+	var (
+		list scanner.ErrorList
+		pos  interface {
+			Line() int
+			Column() int
+		}
+	)
+	switch {
+	case errors.As(err, &list):
+		for _, x := range list {
+			d.Positions = append(d.Positions, &position{
+				Line:    x.Pos.Line,
+				Column:  x.Pos.Column,
+				Message: x.Msg,
+			})
+		}
+	case errors.As(err, &pos):
+		d.Positions = append(d.Positions, &position{
+			Line:   pos.Line(),
+			Column: pos.Column(),
+		})
+	}
+	return d
+}
+
+// errorReporter reports result of a subcommand
+type errorReporter struct {
+	errorFormat string
+}
+
+func (s *errorReporter) setFlags(fs *flag.FlagSet) {
+	fs.StringVar(&s.errorFormat, "error-format", errorFormatText, "error output format: text or json")
+}
+
+// validate returns error if flags are invalid
+func (s *errorReporter) validate() error {
+	switch s.errorFormat {
+	case errorFormatText, errorFormatJSON:
+		return nil
+	}
+	return errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("invalid error format: %s", s.errorFormat))
+}
+
+// report writes err and returns exit status by code of err
+func (s *errorReporter) report(logger log.Logger, elapsed time.Duration, err error) subcommands.ExitStatus {
+	if s.errorFormat != errorFormatJSON {
+		logger.Info("elapsed: %v err: %v", elapsed, err)
+		return exitStatus(err)
+	}
+	logger.Debug("elapsed: %v", elapsed)
+	if err != nil {
+		writeDiagnostic(os.Stderr, err)
+	}
+	return exitStatus(err)
+}
+
+func writeDiagnostic(w io.Writer, err error) {
+	b, e := json.Marshal(newDiagnostic(err))
+	if e != nil {
+		_, _ = fmt.Fprintf(w, "%v\n", err)
+		return
+	}
+	_, _ = fmt.Fprintf(w, "%s\n", b)
+}
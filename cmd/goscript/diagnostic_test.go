@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"testing"
+	"tools/pkg/errors"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/subcommands"
+)
+
+type positionError struct {
+	line, column int
+}
+
+func (s *positionError) Error() string { return fmt.Sprintf("bad position %d:%d", s.line, s.column) }
+func (s *positionError) Line() int     { return s.line }
+func (s *positionError) Column() int   { return s.column }
+
+func TestExitStatus(t *testing.T) {
+	for _, tt := range []struct {
+		Comment string
+		Err     error
+		Status  subcommands.ExitStatus
+	}{
+		{Comment: "success", Err: nil, Status: subcommands.ExitSuccess},
+		{Comment: "unknown", Err: fmt.Errorf("plain"), Status: subcommands.ExitFailure},
+		{Comment: "unknown-code", Err: errors.NewError().SetCode(errors.Unknown), Status: subcommands.ExitFailure},
+		{Comment: "system", Err: errors.NewError().SetCode(errors.System), Status: 3},
+		{Comment: "parse", Err: errors.NewError().SetCode(errors.Parse), Status: 4},
+		{Comment: "translate", Err: errors.NewError().SetCode(errors.Translate), Status: 5},
+		{Comment: "io", Err: errors.NewError().SetCode(errors.IO), Status: 6},
+		{Comment: "validate", Err: errors.NewError().SetCode(errors.Validate), Status: 7},
+		{Comment: "iterator", Err: errors.NewError().SetCode(errors.Iterator), Status: 8},
+		{Comment: "conversion", Err: errors.NewError().SetCode(errors.Conversion), Status: 9},
+		{Comment: "fold", Err: errors.NewError().SetCode(errors.Fold), Status: 10},
+		{Comment: "map", Err: errors.NewError().SetCode(errors.Map), Status: 11},
+		{Comment: "filter", Err: errors.NewError().SetCode(errors.Filter), Status: 12},
+		{Comment: "consume", Err: errors.NewError().SetCode(errors.Consume), Status: 13},
+		{Comment: "sort", Err: errors.NewError().SetCode(errors.Sort), Status: 14},
+		{Comment: "lift", Err: errors.NewError().SetCode(errors.Lift), Status: 15},
+		{Comment: "flat", Err: errors.NewError().SetCode(errors.Flat), Status: 16},
+		{Comment: "throttle", Err: errors.NewError().SetCode(errors.Throttle), Status: 17},
+		{Comment: "sample", Err: errors.NewError().SetCode(errors.Sample), Status: 18},
+		{Comment: "peek", Err: errors.NewError().SetCode(errors.Peek), Status: 19},
+		{Comment: "wrapped", Err: fmt.Errorf("outer: %w", errors.NewError().SetCode(errors.IO)), Status: 6},
+	} {
+		t.Run(tt.Comment, func(t *testing.T) {
+			if got := exitStatus(tt.Err); got != tt.Status {
+				t.Errorf("  actual: %d\nexpected: %d", got, tt.Status)
+			}
+		})
+	}
+}
+
+func TestWriteDiagnostic(t *testing.T) {
+	_, parseErr := parser.ParseExpr("func(s string) string {")
+	if parseErr == nil {
+		t.Fatal("should be parse error")
+	}
+
+	for _, tt := range []struct {
+		Comment string
+		Err     error
+		Result  map[string]interface{}
+	}{
+		{
+			Comment: "plain",
+			Err:     fmt.Errorf("plain"),
+			Result: map[string]interface{}{
+				"code":    "Unknown",
+				"exit":    float64(1),
+				"message": "plain",
+			},
+		},
+		{
+			Comment: "scanner",
+			Err:     errors.NewError().SetCode(errors.Parse).SetError(parseErr),
+			Result: map[string]interface{}{
+				"code":    "Parse",
+				"exit":    float64(4),
+				"message": "Parse 1:24: expected '}', found 'EOF'",
+				"positions": []interface{}{
+					map[string]interface{}{
+						"line":    float64(1),
+						"column":  float64(24),
+						"message": "expected '}', found 'EOF'",
+					},
+				},
+			},
+		},
+		{
+			Comment: "position",
+			Err:     errors.NewError().SetCode(errors.IO).SetError(&positionError{line: 3, column: 7}),
+			Result: map[string]interface{}{
+				"code":    "IO",
+				"exit":    float64(6),
+				"message": "IO bad position 3:7",
+				"positions": []interface{}{
+					map[string]interface{}{
+						"line":   float64(3),
+						"column": float64(7),
+					},
+				},
+			},
+		},
+	} {
+		t.Run(tt.Comment, func(t *testing.T) {
+			var buf bytes.Buffer
+			writeDiagnostic(&buf, tt.Err)
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("not JSON: %v %s", err, buf.String())
+			}
+			if diff := cmp.Diff(tt.Result, got); diff != "" {
+				t.Errorf("not expected diagnostic (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -26,12 +26,20 @@ import (
 //     panic(err)
 //   }
 // }
-func generateMainPipe(mainProc string) *ast.FuncDecl {
+//
+// if decompress, scanner reads stdin through decompressor:
+//   r, err := compress.NewReader(os.Stdin)
+//   if err != nil {
+//     panic(err)
+//   }
+//   sc := bufio.NewScanner(r)
+func generateMainPipe(mainProc string, decompress bool) *ast.FuncDecl {
+	stmts, src := generateStdinReader(decompress)
 	return &ast.FuncDecl{
 		Name: ast.NewIdent("main"),
 		Type: &ast.FuncType{},
 		Body: &ast.BlockStmt{
-			List: []ast.Stmt{
+			List: append(stmts, []ast.Stmt{
 				&ast.AssignStmt{
 					Lhs: []ast.Expr{
 						ast.NewIdent("sc"),
@@ -44,10 +52,7 @@ func generateMainPipe(mainProc string) *ast.FuncDecl {
 								Sel: ast.NewIdent("NewScanner"),
 							},
 							Args: []ast.Expr{
-								&ast.SelectorExpr{
-									X:   ast.NewIdent("os"),
-									Sel: ast.NewIdent("Stdin"),
-								},
+								src,
 							},
 						},
 					},
@@ -172,11 +177,65 @@ func generateMainPipe(mainProc string) *ast.FuncDecl {
 						},
 					},
 				},
-			},
+			}...),
 		},
 	}
 }
 
+// compressImportPath is the package used by generated code to decompress stdin
+const compressImportPath = "tools/pkg/io/compress"
+
+// generateStdinReader generates statements that prepare reader of stdin
+// and expression of the reader
+func generateStdinReader(decompress bool) ([]ast.Stmt, ast.Expr) {
+	stdin := &ast.SelectorExpr{
+		X:   ast.NewIdent("os"),
+		Sel: ast.NewIdent("Stdin"),
+	}
+	if !decompress {
+		return []ast.Stmt{}, stdin
+	}
+	return []ast.Stmt{
+		&ast.AssignStmt{
+			Lhs: []ast.Expr{
+				ast.NewIdent("r"),
+				ast.NewIdent("err"),
+			},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{
+				&ast.CallExpr{
+					Fun: &ast.SelectorExpr{
+						X:   ast.NewIdent("compress"),
+						Sel: ast.NewIdent("NewReader"),
+					},
+					Args: []ast.Expr{
+						stdin,
+					},
+				},
+			},
+		},
+		&ast.IfStmt{
+			Cond: &ast.BinaryExpr{
+				X:  ast.NewIdent("err"),
+				Op: token.NEQ,
+				Y:  ast.NewIdent("nil"),
+			},
+			Body: &ast.BlockStmt{
+				List: []ast.Stmt{
+					&ast.ExprStmt{
+						X: &ast.CallExpr{
+							Fun: ast.NewIdent("panic"),
+							Args: []ast.Expr{
+								ast.NewIdent("err"),
+							},
+						},
+					},
+				},
+			},
+		},
+	}, ast.NewIdent("r")
+}
+
 // generateFilePipeFromFuncLit make it executable.
 //
 // signature:
@@ -201,7 +260,7 @@ func generateMainPipe(mainProc string) *ast.FuncDecl {
 //     panic(err)
 //   }
 // }
-func generateFilePipeFromFuncLit(f *ast.FuncLit) *ast.File {
+func generateFilePipeFromFuncLit(f *ast.FuncLit, decompress bool) *ast.File {
 	return &ast.File{
 		Name: ast.NewIdent("main"),
 		Decls: []ast.Decl{
@@ -218,7 +277,7 @@ func generateFilePipeFromFuncLit(f *ast.FuncLit) *ast.File {
 					},
 				},
 			},
-			generateMainPipe("Main"),
+			generateMainPipe("Main", decompress),
 		},
 	}
 }
@@ -7,22 +7,28 @@ import (
 	"tools/pkg/errors"
 )
 
-func translateMainPipe(f *ast.File, mainProc string) (*ast.File, errors.Error) {
+func translateMainPipe(f *ast.File, mainProc string, decompress bool) (*ast.File, errors.Error) {
 	if fd := goast.FindFuncDecl(f.Decls, "main", nil, nil, nil); fd != nil {
 		return nil, errors.NewError().SetCode(errors.Translate).SetError(fmt.Errorf("func main() found at %v", fd.Pos()))
 	}
 	if goast.FindFuncDecl(f.Decls, mainProc, nil, []string{"string"}, []string{"string", "error"}) == nil {
 		return nil, errors.NewError().SetCode(errors.Translate).SetError(fmt.Errorf("func %s(string) (string, error) not found", mainProc))
 	}
-	f.Decls = append(f.Decls, generateMainPipe(mainProc))
+	f.Decls = append(f.Decls, generateMainPipe(mainProc, decompress))
+	if decompress {
+		f.Decls = append([]ast.Decl{goast.GenerateImports([]string{compressImportPath})}, f.Decls...)
+	}
 	return f, nil
 }
 
-func translateFilePipeFromFuncLit(f ast.Expr, importSpecs []string) (*ast.File, errors.Error) {
+func translateFilePipeFromFuncLit(f ast.Expr, importSpecs []string, decompress bool) (*ast.File, errors.Error) {
 	if !goast.ValidateFuncLit(f, []string{"string"}, []string{"string", "error"}) {
 		return nil, errors.NewError().SetCode(errors.Translate).SetError(fmt.Errorf("no function literal"))
 	}
-	r := generateFilePipeFromFuncLit(f.(*ast.FuncLit))
+	r := generateFilePipeFromFuncLit(f.(*ast.FuncLit), decompress)
+	if decompress {
+		importSpecs = append(importSpecs, compressImportPath)
+	}
 	if len(importSpecs) == 0 {
 		return r, nil
 	}
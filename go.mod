@@ -1,9 +1,10 @@
 module tools
 
-go 1.12
+go 1.22
 
 require (
 	github.com/google/go-cmp v0.3.1
 	github.com/google/subcommands v0.0.0-20190904161856-24aea2b9b9c1
+	github.com/klauspost/compress v1.18.0
 	golang.org/x/tools v0.0.0-20191213221258-04c2e8eff935 // indirect
 )
@@ -0,0 +1,82 @@
+/*
+Package clock provides clock abstraction
+*/
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// Clock tells time and waits
+	Clock interface {
+		// Now returns current time
+		Now() time.Time
+		// Sleep pauses for at least the duration
+		Sleep(d time.Duration)
+	}
+
+	systemClock struct{}
+)
+
+// New returns clock backed by time package
+func New() Clock {
+	return &systemClock{}
+}
+
+func (*systemClock) Now() time.Time        { return time.Now() }
+func (*systemClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+type (
+	// Fake is a clock that moves only when told.
+	// Sleep advances the clock instead of waiting
+	Fake interface {
+		Clock
+		// Advance moves the clock forward
+		Advance(d time.Duration)
+		// Slept returns total duration passed to Sleep
+		Slept() time.Duration
+	}
+
+	fakeClock struct {
+		mux   sync.Mutex
+		now   time.Time
+		slept time.Duration
+	}
+)
+
+// NewFake returns fake clock that starts at t
+func NewFake(t time.Time) Fake {
+	return &fakeClock{
+		now: t,
+	}
+}
+
+func (s *fakeClock) Now() time.Time {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.now
+}
+
+func (s *fakeClock) Sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.now = s.now.Add(d)
+	s.slept += d
+}
+
+func (s *fakeClock) Advance(d time.Duration) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.now = s.now.Add(d)
+}
+
+func (s *fakeClock) Slept() time.Duration {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.slept
+}
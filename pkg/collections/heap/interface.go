@@ -0,0 +1,73 @@
+package heap
+
+import (
+	"container/heap"
+	"fmt"
+)
+
+type (
+	// Heap is a priority queue, Pop yields the least element first
+	Heap interface {
+		Push(interface{})
+		Pop() (interface{}, error)
+		Peep() (interface{}, error)
+		Len() int
+	}
+
+	sliceHeap struct {
+		instance *instance
+	}
+
+	instance struct {
+		elems []interface{}
+		less  func(x, y interface{}) bool
+	}
+)
+
+var (
+	errEmpty = fmt.Errorf("empty")
+)
+
+func (s *instance) Len() int           { return len(s.elems) }
+func (s *instance) Less(i, j int) bool { return s.less(s.elems[i], s.elems[j]) }
+func (s *instance) Swap(i, j int)      { s.elems[i], s.elems[j] = s.elems[j], s.elems[i] }
+func (s *instance) Push(x interface{}) { s.elems = append(s.elems, x) }
+func (s *instance) Pop() interface{} {
+	n := len(s.elems) - 1
+	x := s.elems[n]
+	s.elems[n] = nil
+	s.elems = s.elems[:n]
+	return x
+}
+
+// New returns a heap ordered by less
+func New(less func(x, y interface{}) bool) Heap {
+	return &sliceHeap{
+		instance: &instance{
+			elems: []interface{}{},
+			less:  less,
+		},
+	}
+}
+
+func (s *sliceHeap) Len() int {
+	return s.instance.Len()
+}
+
+func (s *sliceHeap) Peep() (interface{}, error) {
+	if s.Len() == 0 {
+		return nil, errEmpty
+	}
+	return s.instance.elems[0], nil
+}
+
+func (s *sliceHeap) Push(v interface{}) {
+	heap.Push(s.instance, v)
+}
+
+func (s *sliceHeap) Pop() (interface{}, error) {
+	if s.Len() == 0 {
+		return nil, errEmpty
+	}
+	return heap.Pop(s.instance), nil
+}
@@ -4,8 +4,10 @@ Package reflection provides reflection utilties
 package reflection
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"strings"
 	"tools/pkg/errors"
 )
 
@@ -18,32 +20,98 @@ var (
 	}
 )
 
+type (
+	// Option changes option of conversion
+	Option func(*convertConfig)
+
+	convertConfig struct {
+		defaultConverter func(reflect.Value, reflect.Type) reflect.Value
+		registry         Registry
+		strict           bool
+		ctx              context.Context
+		onError          func(error)
+	}
+)
+
+// WithContext stops goroutines converting chan when ctx is done.
+// default: context.Background()
+func WithContext(ctx context.Context) Option {
+	return func(s *convertConfig) {
+		s.ctx = ctx
+	}
+}
+
+// WithErrorHandler invokes f on error of goroutines converting chan.
+// converted chan is closed on error
+func WithErrorHandler(f func(error)) Option {
+	return func(s *convertConfig) {
+		s.onError = f
+	}
+}
+
+// WithStrict converts numbers without overflow, sign and fractional loss,
+// returns error with code errors.Conversion on loss
+func WithStrict() Option {
+	return func(s *convertConfig) {
+		s.strict = true
+	}
+}
+
+// WithRegistry consults r instead of DefaultRegistry, nil disables registry
+func WithRegistry(r Registry) Option {
+	return func(s *convertConfig) {
+		s.registry = r
+	}
+}
+
 // ConvertShallow converts value into specified type but deepest elements are not conversion target
-func ConvertShallow(v interface{}, t reflect.Type) (reflect.Value, errors.Error) {
-	return newConverter(v, shallowDefaultConverter).convert(t)
+// except conversions in registry
+func ConvertShallow(v interface{}, t reflect.Type, options ...Option) (reflect.Value, errors.Error) {
+	return newConverter(v, newConvertConfig(shallowDefaultConverter, options)).convert(t)
 }
 
-// Convert converts value into specified type, v as t
-func Convert(v interface{}, t reflect.Type) (reflect.Value, errors.Error) {
-	return newConverter(v, defaultConverter).convert(t)
+// Convert converts value into specified type, v as t.
+// conversions in registry are consulted before reflect.Value.Convert
+func Convert(v interface{}, t reflect.Type, options ...Option) (reflect.Value, errors.Error) {
+	return newConverter(v, newConvertConfig(defaultConverter, options)).convert(t)
+}
+
+func newConvertConfig(defaultConverter func(reflect.Value, reflect.Type) reflect.Value, options []Option) *convertConfig {
+	c := &convertConfig{
+		defaultConverter: defaultConverter,
+		registry:         DefaultRegistry,
+		ctx:              context.Background(),
+	}
+	for _, opt := range options {
+		opt(c)
+	}
+	return c
 }
 
 type (
 	converter struct {
-		v                interface{}
-		defaultConverter func(reflect.Value, reflect.Type) reflect.Value
+		v    interface{}
+		path string
+		conf *convertConfig
 	}
 )
 
-func newConverter(v interface{}, defaultConverter func(reflect.Value, reflect.Type) reflect.Value) *converter {
+func newConverter(v interface{}, conf *convertConfig) *converter {
 	return &converter{
-		v:                v,
-		defaultConverter: defaultConverter,
+		v:    v,
+		conf: conf,
 	}
 }
 
 func (s *converter) newConverter(v interface{}) *converter {
-	return newConverter(v, s.defaultConverter)
+	return newConverter(v, s.conf)
+}
+
+// newChild returns converter for an element of the value, name is appended to the path
+func (s *converter) newChild(v interface{}, name string) *converter {
+	c := s.newConverter(v)
+	c.path = s.path + name
+	return c
 }
 
 func (s *converter) valueOf() reflect.Value {
@@ -54,47 +122,334 @@ func (s *converter) typeOf() reflect.Type {
 	return reflect.TypeOf(s.v)
 }
 
+func (s *converter) newError(err interface{}) errors.Error {
+	return s.newCodeError(errors.Validate, err)
+}
+
+func (s *converter) newCodeError(c errors.Code, err interface{}) errors.Error {
+	cause, ok := err.(error)
+	if !ok {
+		cause = fmt.Errorf("%v", err)
+	}
+	if s.path == "" {
+		return errors.NewError().SetCode(c).SetError(fmt.Errorf("invalid conversion: %w", cause))
+	}
+	return errors.NewError().SetCode(c).SetError(fmt.Errorf("invalid conversion at %s: %w", s.path, cause))
+}
+
+func isNilable(k reflect.Kind) bool {
+	switch k {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+		return true
+	}
+	return false
+}
+
 func (s *converter) convert(t reflect.Type) (ret reflect.Value, e errors.Error) {
 	defer func() {
 		if err := recover(); err != nil {
 			ret = reflect.Zero(t)
-			e = errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("invalid conversion: %v", err))
+			e = s.newError(err)
 		}
 	}()
 
+	sv := s.valueOf()
+	if !sv.IsValid() && isNilable(t.Kind()) {
+		return reflect.Zero(t), nil
+	}
+	if sv.IsValid() && s.conf.registry != nil {
+		if f, ok := s.conf.registry.Lookup(sv.Type(), t); ok {
+			x, err := f(sv)
+			if err != nil {
+				return reflect.Zero(t), s.newCodeError(errors.Conversion, err)
+			}
+			return x, nil
+		}
+	}
+
 	switch t.Kind() {
 	case reflect.Array:
 		return s.convertArray(t)
 	case reflect.Chan:
 		return s.convertChan(t)
 	case reflect.Map:
+		if sv.Kind() == reflect.Struct || sv.Kind() == reflect.Ptr {
+			return s.convertStructToMap(t)
+		}
 		return s.convertMap(t)
 	case reflect.Slice:
 		return s.convertSlice(t)
+	case reflect.Ptr:
+		return s.convertPtr(t)
+	case reflect.Interface:
+		return s.convertInterface(t)
+	case reflect.Struct:
+		return s.convertStruct(t)
 	default:
-		if s.defaultConverter != nil {
-			return s.defaultConverter(s.valueOf(), t), nil
+		if sv.Kind() == reflect.Ptr {
+			return s.convertDeref(t)
+		}
+		if s.conf.strict && isNumber(sv.Kind()) && isNumber(t.Kind()) {
+			return s.convertNumber(sv, t)
+		}
+		if s.conf.defaultConverter != nil {
+			return s.conf.defaultConverter(sv, t), nil
+		}
+		return sv, nil
+	}
+}
+
+// convertDeref converts the value pointed by the pointer
+func (s *converter) convertDeref(t reflect.Type) (reflect.Value, errors.Error) {
+	sv := s.valueOf()
+	if sv.IsNil() {
+		return reflect.Zero(t), s.newError(fmt.Sprintf("nil %v into %v", sv.Type(), t))
+	}
+	c := s.newConverter(sv.Elem().Interface())
+	c.path = s.path
+	return c.convert(t)
+}
+
+func (s *converter) convertPtr(t reflect.Type) (reflect.Value, errors.Error) {
+	sv := s.valueOf()
+	if sv.Type().AssignableTo(t) {
+		return sv, nil
+	}
+	if sv.Kind() == reflect.Ptr {
+		if sv.IsNil() {
+			return reflect.Zero(t), nil
 		}
-		return s.valueOf(), nil
+		sv = sv.Elem()
 	}
+	c := s.newConverter(sv.Interface())
+	c.path = s.path
+	x, err := c.convert(t.Elem())
+	if err != nil {
+		return reflect.Zero(t), err
+	}
+	p := reflect.New(t.Elem())
+	p.Elem().Set(x)
+	return p, nil
+}
+
+func (s *converter) convertInterface(t reflect.Type) (reflect.Value, errors.Error) {
+	sv := s.valueOf()
+	if !sv.Type().Implements(t) {
+		return reflect.Zero(t), s.newError(fmt.Sprintf("%v does not implement %v", sv.Type(), t))
+	}
+	x := reflect.New(t).Elem()
+	x.Set(sv)
+	return x, nil
+}
+
+// fieldNameOf returns name of field by json tag or field name, false if the field is ignored
+func fieldNameOf(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" {
+		return "", false
+	}
+	tag, ok := f.Tag.Lookup("json")
+	if !ok {
+		return f.Name, true
+	}
+	name := strings.Split(tag, ",")[0]
+	switch name {
+	case "-":
+		return "", false
+	case "":
+		return f.Name, true
+	}
+	return name, true
+}
+
+// findField returns index of field matched by name case-insensitively, nil if not found
+func findField(t reflect.Type, name string) []int {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if n, ok := fieldNameOf(f); ok && strings.EqualFold(n, name) {
+			return f.Index
+		}
+	}
+	return nil
+}
+
+func (s *converter) convertStruct(t reflect.Type) (reflect.Value, errors.Error) {
+	sv := s.valueOf()
+	if sv.Type().AssignableTo(t) {
+		return sv, nil
+	}
+	switch sv.Kind() {
+	case reflect.Ptr:
+		return s.convertDeref(t)
+	case reflect.Map:
+		if sv.Type().Key().Kind() != reflect.String {
+			return reflect.Zero(t), s.newError(fmt.Sprintf("map key %v into %v", sv.Type().Key(), t))
+		}
+		r := reflect.New(t).Elem()
+		mapIter := sv.MapRange()
+		for mapIter.Next() {
+			k := mapIter.Key().String()
+			index := findField(t, k)
+			if index == nil {
+				continue
+			}
+			f := r.FieldByIndex(index)
+			x, err := s.newChild(mapIter.Value().Interface(), "."+k).convert(f.Type())
+			if err != nil {
+				return reflect.Zero(t), err
+			}
+			f.Set(x)
+		}
+		return r, nil
+	case reflect.Struct:
+		r := reflect.New(t).Elem()
+		st := sv.Type()
+		for i := 0; i < st.NumField(); i++ {
+			name, ok := fieldNameOf(st.Field(i))
+			if !ok {
+				continue
+			}
+			index := findField(t, name)
+			if index == nil {
+				continue
+			}
+			f := r.FieldByIndex(index)
+			x, err := s.newChild(sv.Field(i).Interface(), "."+name).convert(f.Type())
+			if err != nil {
+				return reflect.Zero(t), err
+			}
+			f.Set(x)
+		}
+		return r, nil
+	}
+	if s.conf.defaultConverter != nil {
+		return s.conf.defaultConverter(sv, t), nil
+	}
+	return sv, nil
+}
+
+func (s *converter) convertStructToMap(t reflect.Type) (reflect.Value, errors.Error) {
+	sv := s.valueOf()
+	if sv.Kind() == reflect.Ptr {
+		return s.convertDeref(t)
+	}
+	if t.Key().Kind() != reflect.String {
+		return reflect.Zero(t), s.newError(fmt.Sprintf("%v into map key %v", sv.Type(), t.Key()))
+	}
+	st := sv.Type()
+	r := reflect.MakeMapWithSize(t, st.NumField())
+	for i := 0; i < st.NumField(); i++ {
+		name, ok := fieldNameOf(st.Field(i))
+		if !ok {
+			continue
+		}
+		x, err := s.newChild(sv.Field(i).Interface(), "."+name).convert(t.Elem())
+		if err != nil {
+			return reflect.Zero(t), err
+		}
+		r.SetMapIndex(reflect.ValueOf(name).Convert(t.Key()), x)
+	}
+	return r, nil
 }
 
+// convertChan converts chan, slice or array into chan.
+// elements of chan are converted by a goroutine until source is closed or context is done,
+// the converted chan is closed then
 func (s *converter) convertChan(t reflect.Type) (reflect.Value, errors.Error) {
-	return reflect.MakeChan(t, s.valueOf().Cap()), nil
+	sv := s.valueOf()
+	if sv.Type().AssignableTo(t) {
+		return sv, nil
+	}
+	bt := reflect.ChanOf(reflect.BothDir, t.Elem())
+	switch sv.Kind() {
+	case reflect.Slice, reflect.Array:
+		ch := reflect.MakeChan(bt, sv.Len())
+		for i := 0; i < sv.Len(); i++ {
+			y, err := s.newChild(sv.Index(i).Interface(), fmt.Sprintf("[%d]", i)).convert(t.Elem())
+			if err != nil {
+				return reflect.Zero(t), err
+			}
+			ch.Send(y)
+		}
+		ch.Close()
+		return ch.Convert(t), nil
+	case reflect.Chan:
+		if sv.Type().ChanDir()&reflect.RecvDir == 0 {
+			return reflect.Zero(t), s.newError(fmt.Sprintf("cannot receive from %v", sv.Type()))
+		}
+	default:
+		return reflect.Zero(t), s.newError(fmt.Sprintf("%v into %v", sv.Type(), t))
+	}
+	ch := reflect.MakeChan(bt, sv.Cap())
+	go s.bridgeChan(sv, ch, t.Elem())
+	return ch.Convert(t), nil
 }
 
+// bridgeChan sends converted elements received from src to dst
+func (s *converter) bridgeChan(src, dst reflect.Value, t reflect.Type) {
+	defer dst.Close()
+	var (
+		ctx  = s.conf.ctx
+		done = reflect.SelectCase{
+			Dir:  reflect.SelectRecv,
+			Chan: reflect.ValueOf(ctx.Done()),
+		}
+	)
+	for i := 0; ; i++ {
+		chosen, x, ok := reflect.Select([]reflect.SelectCase{
+			done,
+			{
+				Dir:  reflect.SelectRecv,
+				Chan: src,
+			},
+		})
+		if chosen == 0 || !ok {
+			return
+		}
+		y, err := s.newChild(x.Interface(), fmt.Sprintf("[%d]", i)).convert(t)
+		if err != nil {
+			if s.conf.onError != nil {
+				s.conf.onError(err)
+			}
+			return
+		}
+		if chosen, _, _ := reflect.Select([]reflect.SelectCase{
+			done,
+			{
+				Dir:  reflect.SelectSend,
+				Chan: dst,
+				Send: y,
+			},
+		}); chosen == 0 {
+			return
+		}
+	}
+}
+
+// convertArray converts slice or array into array.
+// fails if source is longer than the array, the rest is zero if source is shorter
 func (s *converter) convertArray(t reflect.Type) (reflect.Value, errors.Error) {
 	sv := s.valueOf()
-	arrayPtr := reflect.New(t)
-	for i := 0; i < arrayPtr.Len(); i++ {
+	if sv.Type().AssignableTo(t) {
+		return sv, nil
+	}
+	switch sv.Kind() {
+	case reflect.Slice, reflect.Array:
+	default:
+		return reflect.Zero(t), s.newError(fmt.Sprintf("%v into %v", sv.Type(), t))
+	}
+	if sv.Len() > t.Len() {
+		return reflect.Zero(t), s.newError(fmt.Sprintf("length %d exceeds %v", sv.Len(), t))
+	}
+	array := reflect.New(t).Elem()
+	for i := 0; i < sv.Len(); i++ {
 		x := sv.Index(i)
-		y, err := s.newConverter(x.Interface()).convert(t.Elem())
+		y, err := s.newChild(x.Interface(), fmt.Sprintf("[%d]", i)).convert(t.Elem())
 		if err != nil {
 			return reflect.Zero(t), err
 		}
-		arrayPtr.Index(i).Set(y)
+		array.Index(i).Set(y)
 	}
-	return arrayPtr, nil
+	return array, nil
 }
 
 func (s *converter) convertSlice(t reflect.Type) (reflect.Value, errors.Error) {
@@ -102,7 +457,7 @@ func (s *converter) convertSlice(t reflect.Type) (reflect.Value, errors.Error) {
 	slicePtr := reflect.MakeSlice(t, sv.Len(), sv.Len())
 	for i := 0; i < sv.Len(); i++ {
 		x := sv.Index(i)
-		y, err := s.newConverter(x.Interface()).convert(t.Elem())
+		y, err := s.newChild(x.Interface(), fmt.Sprintf("[%d]", i)).convert(t.Elem())
 		if err != nil {
 			return reflect.Zero(t), err
 		}
@@ -117,8 +472,8 @@ func (s *converter) convertMap(t reflect.Type) (reflect.Value, errors.Error) {
 	mapIter := sv.MapRange()
 	for mapIter.Next() {
 		k, v := mapIter.Key(), mapIter.Value()
-		ck, keyErr := s.newConverter(k.Interface()).convert(t.Key())
-		cv, valueErr := s.newConverter(v.Interface()).convert(t.Elem())
+		ck, keyErr := s.newChild(k.Interface(), fmt.Sprintf("[%v]", k.Interface())).convert(t.Key())
+		cv, valueErr := s.newChild(v.Interface(), fmt.Sprintf("[%v]", k.Interface())).convert(t.Elem())
 		if keyErr != nil {
 			return reflect.Zero(t), keyErr
 		}
@@ -0,0 +1,425 @@
+package reflection_test
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"tools/pkg/conv/reflection"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type (
+	address struct {
+		City string `json:"city"`
+	}
+
+	person struct {
+		Name    string   `json:"name"`
+		Age     int      `json:"age,omitempty"`
+		Address *address `json:"address"`
+		Ignored string   `json:"-"`
+	}
+
+	member struct {
+		Name string
+		Age  int64
+	}
+
+	convertTestcase struct {
+		Comment string
+		Value   interface{}
+		Type    reflect.Type
+		Options []reflection.Option
+		Shallow bool
+		Result  interface{}
+		Error   string
+	}
+)
+
+func (s *convertTestcase) Test(t *testing.T) {
+	convert := reflection.Convert
+	if s.Shallow {
+		convert = reflection.ConvertShallow
+	}
+	v, err := convert(s.Value, s.Type, s.Options...)
+	if s.Error != "" {
+		if err == nil {
+			t.Fatal("should be error")
+		}
+		if !strings.Contains(err.Error(), s.Error) {
+			t.Errorf("not expected error: %v", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(v.Interface(), s.Result) {
+		t.Errorf("not expected result:\n  actual(%#v)\nexpected(%#v)", v.Interface(), s.Result)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	testcases := []*convertTestcase{
+		{
+			Comment: "map-to-struct",
+			Value: map[string]interface{}{
+				"name":    "Stela",
+				"AGE":     float64(20),
+				"address": map[string]interface{}{"city": "Bucharest"},
+				"Ignored": "x",
+			},
+			Type:   reflect.TypeOf(person{}),
+			Result: person{Name: "Stela", Age: 20, Address: &address{City: "Bucharest"}},
+		},
+		{
+			Comment: "slice-of-maps-to-pointers",
+			Value: []interface{}{
+				map[string]interface{}{"name": "Stela"},
+				nil,
+			},
+			Type:   reflect.TypeOf([]*person{}),
+			Result: []*person{{Name: "Stela"}, nil},
+		},
+		{
+			Comment: "struct-to-map",
+			Value:   &person{Name: "Aud", Age: 30},
+			Type:    reflect.TypeOf(map[string]interface{}{}),
+			Result: map[string]interface{}{
+				"name":    "Aud",
+				"age":     30,
+				"address": (*address)(nil),
+			},
+		},
+		{
+			Comment: "struct-to-struct",
+			Value:   person{Name: "Aud", Age: 30},
+			Type:    reflect.TypeOf(member{}),
+			Result:  member{Name: "Aud", Age: 30},
+		},
+		{
+			Comment: "deref",
+			Value:   func() *int { x := 1; return &x }(),
+			Type:    reflect.TypeOf(float64(0)),
+			Result:  float64(1),
+		},
+		{
+			Comment: "field-path",
+			Value: []interface{}{
+				map[string]interface{}{"name": "Stela"},
+				map[string]interface{}{"address": map[string]interface{}{"city": 1.5}},
+			},
+			Type:  reflect.TypeOf([]person{}),
+			Error: "at [1].address.city:",
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.Comment, func(t *testing.T) {
+			tt.Test(t)
+		})
+	}
+}
+
+type celsius float64
+
+func TestConvertRegistry(t *testing.T) {
+	registry := reflection.NewRegistry()
+	if err := registry.Register(func(x string) (celsius, error) {
+		var f float64
+		err := json.Unmarshal([]byte(strings.TrimSuffix(x, "C")), &f)
+		return celsius(f), err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Register(func(x string) {}); err == nil {
+		t.Error("should be error")
+	}
+
+	testcases := []*convertTestcase{
+		{
+			Comment: "parse-numbers",
+			Value:   []string{"1", "-2"},
+			Type:    reflect.TypeOf([]int8{}),
+			Result:  []int8{1, -2},
+		},
+		{
+			Comment: "parse-overflow",
+			Value:   []string{"1", "300"},
+			Type:    reflect.TypeOf([]int8{}),
+			Error:   "at [1]:",
+		},
+		{
+			Comment: "parse-struct",
+			Value: map[string]interface{}{
+				"at":      "2020-01-02T03:04:05Z",
+				"timeout": "1m30s",
+				"ok":      "true",
+				"rate":    json.Number("1.5"),
+				"name":    []byte("x"),
+			},
+			Type: reflect.TypeOf(struct {
+				At      time.Time     `json:"at"`
+				Timeout time.Duration `json:"timeout"`
+				OK      bool          `json:"ok"`
+				Rate    float64       `json:"rate"`
+				Name    string        `json:"name"`
+			}{}),
+			Result: struct {
+				At      time.Time     `json:"at"`
+				Timeout time.Duration `json:"timeout"`
+				OK      bool          `json:"ok"`
+				Rate    float64       `json:"rate"`
+				Name    string        `json:"name"`
+			}{
+				At:      time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+				Timeout: 90 * time.Second,
+				OK:      true,
+				Rate:    1.5,
+				Name:    "x",
+			},
+		},
+		{
+			Comment: "shallow",
+			Value:   "12",
+			Type:    reflect.TypeOf(0),
+			Shallow: true,
+			Result:  12,
+		},
+		{
+			Comment: "custom-registry",
+			Value:   "21.5C",
+			Type:    reflect.TypeOf(celsius(0)),
+			Options: []reflection.Option{reflection.WithRegistry(registry)},
+			Result:  celsius(21.5),
+		},
+		{
+			Comment: "no-registry",
+			Value:   "12",
+			Type:    reflect.TypeOf(0),
+			Options: []reflection.Option{reflection.WithRegistry(nil)},
+			Error:   "invalid conversion",
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.Comment, func(t *testing.T) {
+			tt.Test(t)
+		})
+	}
+}
+
+func TestConvertStrict(t *testing.T) {
+	strict := []reflection.Option{reflection.WithStrict()}
+	testcases := []*convertTestcase{
+		{
+			Comment: "int-narrowing",
+			Value:   []int64{1, -128, 127},
+			Type:    reflect.TypeOf([]int8{}),
+			Options: strict,
+			Result:  []int8{1, -128, 127},
+		},
+		{
+			Comment: "int-overflow",
+			Value:   []int64{1, 128},
+			Type:    reflect.TypeOf([]int8{}),
+			Options: strict,
+			Error:   "at [1]: 128 of int64 into int8: overflow",
+		},
+		{
+			Comment: "sign",
+			Value:   -1,
+			Type:    reflect.TypeOf(uint(0)),
+			Options: strict,
+			Error:   "negative",
+		},
+		{
+			Comment: "uint-overflow",
+			Value:   uint64(math.MaxUint64),
+			Type:    reflect.TypeOf(int64(0)),
+			Options: strict,
+			Error:   "overflow",
+		},
+		{
+			Comment: "float-integral",
+			Value:   []float64{2, -3},
+			Type:    reflect.TypeOf([]int{}),
+			Options: strict,
+			Result:  []int{2, -3},
+		},
+		{
+			Comment: "float-fractional",
+			Value:   1.5,
+			Type:    reflect.TypeOf(0),
+			Options: strict,
+			Error:   "fractional",
+		},
+		{
+			Comment: "float-range",
+			Value:   float64(1 << 16),
+			Type:    reflect.TypeOf(int16(0)),
+			Options: strict,
+			Error:   "overflow",
+		},
+		{
+			Comment: "float32-overflow",
+			Value:   math.MaxFloat64,
+			Type:    reflect.TypeOf(float32(0)),
+			Options: strict,
+			Error:   "overflow",
+		},
+		{
+			Comment: "precision-loss",
+			Value:   int64(1<<53 + 1),
+			Type:    reflect.TypeOf(float64(0)),
+			Options: strict,
+			Error:   "precision loss",
+		},
+		{
+			Comment: "precision-loss-max-uint64",
+			Value:   uint64(math.MaxUint64),
+			Type:    reflect.TypeOf(float64(0)),
+			Options: strict,
+			Error:   "precision loss",
+		},
+		{
+			Comment: "precision-loss-max-int64",
+			Value:   int64(math.MaxInt64),
+			Type:    reflect.TypeOf(float32(0)),
+			Options: strict,
+			Error:   "precision loss",
+		},
+		{
+			Comment: "exact-min-int64",
+			Value:   int64(math.MinInt64),
+			Type:    reflect.TypeOf(float64(0)),
+			Options: strict,
+			Result:  float64(math.MinInt64),
+		},
+		{
+			Comment: "not-strict",
+			Value:   1.5,
+			Type:    reflect.TypeOf(0),
+			Result:  1,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.Comment, func(t *testing.T) {
+			tt.Test(t)
+		})
+	}
+}
+
+func TestConvertArray(t *testing.T) {
+	testcases := []*convertTestcase{
+		{
+			Comment: "same-length",
+			Value:   []int{1, 2},
+			Type:    reflect.TypeOf([2]float64{}),
+			Result:  [2]float64{1, 2},
+		},
+		{
+			Comment: "shorter",
+			Value:   []interface{}{"a"},
+			Type:    reflect.TypeOf([3]string{}),
+			Result:  [3]string{"a", "", ""},
+		},
+		{
+			Comment: "array",
+			Value:   [2]int{1, 2},
+			Type:    reflect.TypeOf([2]int64{}),
+			Result:  [2]int64{1, 2},
+		},
+		{
+			Comment: "longer",
+			Value:   []int{1, 2, 3},
+			Type:    reflect.TypeOf([2]int{}),
+			Error:   "length 3 exceeds [2]int",
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.Comment, func(t *testing.T) {
+			tt.Test(t)
+		})
+	}
+}
+
+func TestConvertChan(t *testing.T) {
+	receive := func(v reflect.Value) []float64 {
+		r := []float64{}
+		for x := range v.Interface().(<-chan float64) {
+			r = append(r, x)
+		}
+		return r
+	}
+
+	t.Run("chan", func(t *testing.T) {
+		src := make(chan int, 3)
+		src <- 1
+		src <- 2
+		src <- 3
+		close(src)
+		v, err := reflection.Convert(src, reflect.TypeOf((<-chan float64)(nil)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r := receive(v); !cmp.Equal(r, []float64{1, 2, 3}) {
+			t.Errorf("got %v", r)
+		}
+	})
+
+	t.Run("slice", func(t *testing.T) {
+		v, err := reflection.Convert([]interface{}{1, int8(2)}, reflect.TypeOf((<-chan float64)(nil)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r := receive(v); !cmp.Equal(r, []float64{1, 2}) {
+			t.Errorf("got %v", r)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		var (
+			src         = make(chan int)
+			ctx, cancel = context.WithCancel(context.Background())
+		)
+		v, err := reflection.Convert(src, reflect.TypeOf((<-chan float64)(nil)), reflection.WithContext(ctx))
+		if err != nil {
+			t.Fatal(err)
+		}
+		src <- 1
+		cancel()
+		if r := receive(v); len(r) > 1 {
+			t.Errorf("got %v", r)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		var (
+			src  = make(chan interface{}, 2)
+			errC = make(chan error, 1)
+		)
+		src <- 1
+		src <- "x"
+		close(src)
+		v, err := reflection.Convert(src, reflect.TypeOf((<-chan float64)(nil)), reflection.WithErrorHandler(func(err error) {
+			errC <- err
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r := receive(v); !cmp.Equal(r, []float64{1}) {
+			t.Errorf("got %v", r)
+		}
+		if err := <-errC; !strings.Contains(err.Error(), "at [1]:") {
+			t.Errorf("not expected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package reflection
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"tools/pkg/errors"
+)
+
+func isInt(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isUint(k reflect.Kind) bool {
+	switch k {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+func isFloat(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
+
+func isNumber(k reflect.Kind) bool {
+	return isInt(k) || isUint(k) || isFloat(k)
+}
+
+// floatFitsInteger returns true if f is in the range of integer type t
+func floatFitsInteger(f float64, t reflect.Type) bool {
+	bits := float64(t.Bits())
+	if isUint(t.Kind()) {
+		return f >= 0 && f < math.Pow(2, bits)
+	}
+	return f >= -math.Pow(2, bits-1) && f < math.Pow(2, bits-1)
+}
+
+// convertNumber converts number without overflow, sign and fractional loss
+func (s *converter) convertNumber(v reflect.Value, t reflect.Type) (reflect.Value, errors.Error) {
+	var fail = func(reason string) (reflect.Value, errors.Error) {
+		return reflect.Zero(t), s.newCodeError(errors.Conversion, fmt.Sprintf("%v of %v into %v: %s", v.Interface(), v.Type(), t, reason))
+	}
+	switch {
+	case isInt(v.Kind()) && isUint(t.Kind()):
+		if v.Int() < 0 {
+			return fail("negative")
+		}
+	case isFloat(v.Kind()) && (isInt(t.Kind()) || isUint(t.Kind())):
+		f := v.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return fail("not finite")
+		}
+		if f != math.Trunc(f) {
+			return fail("fractional")
+		}
+		if isUint(t.Kind()) && f < 0 {
+			return fail("negative")
+		}
+		if !floatFitsInteger(f, t) {
+			return fail("overflow")
+		}
+		return v.Convert(t), nil
+	case isFloat(v.Kind()) && isFloat(t.Kind()):
+		f := v.Float()
+		if t.Bits() == 32 && !math.IsInf(f, 0) && math.Abs(f) > math.MaxFloat32 {
+			return fail("overflow")
+		}
+		return v.Convert(t), nil
+	}
+	// integers round trip unless overflow, precision loss into float
+	r := v.Convert(t)
+	if isFloat(t.Kind()) && !floatFitsInteger(r.Float(), v.Type()) {
+		// converting out of range float into integer is implementation-defined
+		return fail("precision loss")
+	}
+	if back := r.Convert(v.Type()); back.Interface() != v.Interface() {
+		if isFloat(t.Kind()) {
+			return fail("precision loss")
+		}
+		return fail("overflow")
+	}
+	if isUint(v.Kind()) && isInt(t.Kind()) && r.Int() < 0 {
+		return fail("overflow")
+	}
+	return r, nil
+}
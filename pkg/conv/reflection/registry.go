@@ -0,0 +1,170 @@
+package reflection
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+	"tools/pkg/errors"
+)
+
+type (
+	// Registry holds conversions between types
+	Registry interface {
+		// Register adds conversion f, func(A) B or func(A) (B, error), converts A into B.
+		// replace the conversion if A into B is already registered
+		Register(f interface{}) errors.Error
+		// Lookup returns conversion from into to
+		Lookup(from, to reflect.Type) (Conversion, bool)
+	}
+
+	// Conversion converts value
+	Conversion func(reflect.Value) (reflect.Value, error)
+
+	registry struct {
+		mux         sync.RWMutex
+		conversions map[conversionKey]Conversion
+	}
+
+	conversionKey struct {
+		from reflect.Type
+		to   reflect.Type
+	}
+)
+
+var (
+	InvalidConversion = errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("conversion must be func(A) B or func(A) (B, error)"))
+
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+	// DefaultRegistry is consulted by Convert and ConvertShallow unless WithRegistry.
+	// has parsers from string by strconv, RFC3339 times, durations and json.Number
+	DefaultRegistry = newDefaultRegistry()
+)
+
+// NewRegistry returns an empty registry
+func NewRegistry() Registry {
+	return &registry{
+		conversions: map[conversionKey]Conversion{},
+	}
+}
+
+// Register adds conversion f into DefaultRegistry
+func Register(f interface{}) errors.Error {
+	return DefaultRegistry.Register(f)
+}
+
+func (s *registry) Register(f interface{}) errors.Error {
+	t := reflect.TypeOf(f)
+	if t == nil || t.Kind() != reflect.Func || t.NumIn() != 1 {
+		return InvalidConversion
+	}
+	var (
+		fv        = reflect.ValueOf(f)
+		withError bool
+	)
+	switch t.NumOut() {
+	case 1:
+	case 2:
+		if t.Out(1) != errorType {
+			return InvalidConversion
+		}
+		withError = true
+	default:
+		return InvalidConversion
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.conversions[conversionKey{from: t.In(0), to: t.Out(0)}] = func(v reflect.Value) (reflect.Value, error) {
+		ret := fv.Call([]reflect.Value{v})
+		if withError && !ret[1].IsNil() {
+			return reflect.Zero(t.Out(0)), ret[1].Interface().(error)
+		}
+		return ret[0], nil
+	}
+	return nil
+}
+
+func (s *registry) Lookup(from, to reflect.Type) (Conversion, bool) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	f, ok := s.conversions[conversionKey{from: from, to: to}]
+	return f, ok
+}
+
+func newDefaultRegistry() Registry {
+	r := NewRegistry()
+	for _, f := range []interface{}{
+		func(x string) (int, error) {
+			i, err := strconv.ParseInt(x, 10, 0)
+			return int(i), err
+		},
+		func(x string) (int8, error) {
+			i, err := strconv.ParseInt(x, 10, 8)
+			return int8(i), err
+		},
+		func(x string) (int16, error) {
+			i, err := strconv.ParseInt(x, 10, 16)
+			return int16(i), err
+		},
+		func(x string) (int32, error) {
+			i, err := strconv.ParseInt(x, 10, 32)
+			return int32(i), err
+		},
+		func(x string) (int64, error) {
+			return strconv.ParseInt(x, 10, 64)
+		},
+		func(x string) (uint, error) {
+			i, err := strconv.ParseUint(x, 10, 0)
+			return uint(i), err
+		},
+		func(x string) (uint8, error) {
+			i, err := strconv.ParseUint(x, 10, 8)
+			return uint8(i), err
+		},
+		func(x string) (uint16, error) {
+			i, err := strconv.ParseUint(x, 10, 16)
+			return uint16(i), err
+		},
+		func(x string) (uint32, error) {
+			i, err := strconv.ParseUint(x, 10, 32)
+			return uint32(i), err
+		},
+		func(x string) (uint64, error) {
+			return strconv.ParseUint(x, 10, 64)
+		},
+		func(x string) (float32, error) {
+			f, err := strconv.ParseFloat(x, 32)
+			return float32(f), err
+		},
+		func(x string) (float64, error) {
+			return strconv.ParseFloat(x, 64)
+		},
+		strconv.ParseBool,
+		func(x string) (time.Time, error) {
+			return time.Parse(time.RFC3339Nano, x)
+		},
+		time.ParseDuration,
+		func(x []byte) string {
+			return string(x)
+		},
+		func(x json.Number) (float64, error) {
+			return x.Float64()
+		},
+		func(x json.Number) (int64, error) {
+			return x.Int64()
+		},
+		func(x json.Number) (int, error) {
+			i, err := strconv.ParseInt(string(x), 10, 0)
+			return int(i), err
+		},
+	} {
+		if err := r.Register(f); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
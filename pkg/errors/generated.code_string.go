@@ -24,11 +24,14 @@ func _() {
 	_ = x[Sort-13]
 	_ = x[Lift-14]
 	_ = x[Flat-15]
+	_ = x[Throttle-16]
+	_ = x[Sample-17]
+	_ = x[Peek-18]
 }
 
-const _Code_name = "UnknownNormalSystemParseTranslateIOValidateIteratorConversionFoldMapFilterConsumeSortLiftFlat"
+const _Code_name = "UnknownNormalSystemParseTranslateIOValidateIteratorConversionFoldMapFilterConsumeSortLiftFlatThrottleSamplePeek"
 
-var _Code_index = [...]uint8{0, 7, 13, 19, 24, 33, 35, 43, 51, 61, 65, 68, 74, 81, 85, 89, 93}
+var _Code_index = [...]uint8{0, 7, 13, 19, 24, 33, 35, 43, 51, 61, 65, 68, 74, 81, 85, 89, 93, 101, 107, 111}
 
 func (i Code) String() string {
 	if i < 0 || i >= Code(len(_Code_index)-1) {
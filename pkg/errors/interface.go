@@ -1,21 +1,42 @@
 /*
-Package errors provides extended errors
+Package errors provides extended errors.
+
+errors are immutable, SetCode and SetError return a new error.
+errors work with Is, As and Unwrap of the standard errors package
 */
 package errors
 
-import "fmt"
+import (
+	stderrors "errors"
+	"fmt"
+	"reflect"
+)
 
 type (
 	// Error is extended error
 	Error interface {
 		error
+		// SetCode returns a copy of the error with code c
 		SetCode(c Code) Error
+		// SetError returns a copy of the error with cause err
 		SetError(err error) Error
+		// WithStack returns a copy of the error with stack trace of the caller
+		WithStack() Error
+		// Code returns code of the error
+		Code() Code
+		// Unwrap returns cause of the error
+		Unwrap() error
+		// Is returns true if target is an Error of the same code and the same cause.
+		// target without cause matches any error of the code
+		Is(target error) bool
+		// Stack returns stack trace, empty unless captured
+		Stack() string
 	}
 
 	xError struct {
-		C Code
-		E error
+		C     Code
+		E     error
+		stack []uintptr
 	}
 )
 
@@ -55,22 +76,107 @@ const (
 	Lift
 	// Fla is flat error
 	Flat
+	// Throttle is throttle error
+	Throttle
+	// Sample is sample error
+	Sample
+	// Peek is peek error
+	Peek
 )
 
+// NewError creates an error, captures stack trace if EnableStack
 func NewError() Error {
-	return &xError{}
+	s := &xError{}
+	if stackEnabled() {
+		s.stack = callers(1)
+	}
+	return s
 }
 
-func (s *xError) SetError(err error) Error {
-	s.E = err
+// Wrap creates an error with code c wraps err with message
+func Wrap(c Code, err error, msg string) Error {
+	s := &xError{
+		C: c,
+		E: fmt.Errorf("%s: %w", msg, err),
+	}
+	if stackEnabled() {
+		s.stack = callers(1)
+	}
 	return s
 }
 
-func (s *xError) SetCode(c Code) Error {
-	s.C = c
-	return s
+func (s *xError) clone() *xError {
+	c := *s
+	return &c
+}
+
+func (s *xError) SetError(err error) Error {
+	c := s.clone()
+	c.E = err
+	return c
+}
+
+func (s *xError) SetCode(code Code) Error {
+	c := s.clone()
+	c.C = code
+	return c
+}
+
+func (s *xError) WithStack() Error {
+	c := s.clone()
+	c.stack = callers(1)
+	return c
+}
+
+func (s *xError) Code() Code    { return s.C }
+func (s *xError) Unwrap() error { return s.E }
+func (s *xError) Stack() string { return formatStack(s.stack) }
+func (s *xError) Error() string { return fmt.Sprintf("%v %v", s.C, s.E) }
+
+func (s *xError) Is(target error) bool {
+	t, ok := target.(*xError)
+	return ok && t.C == s.C && (t.E == nil || sameCause(t.E, s.E))
+}
+
+// sameCause compares causes only if they are comparable, comparing others panics
+func sameCause(x, y error) bool {
+	tx := reflect.TypeOf(x)
+	return tx != nil && tx == reflect.TypeOf(y) && tx.Comparable() && x == y
+}
+
+// Format prints stack trace after message by %+v
+func (s *xError) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('+') && len(s.stack) > 0 {
+		fmt.Fprintf(f, "%s\n%s", s.Error(), s.Stack())
+		return
+	}
+	fmt.Fprint(f, s.Error())
+}
+
+// CodeOf returns code of the first Error in the chain of err.
+// Normal if err is nil, Unknown if no Error in the chain
+func CodeOf(err error) Code {
+	if err == nil {
+		return Normal
+	}
+	var e Error
+	if stderrors.As(err, &e) {
+		return e.Code()
+	}
+	return Unknown
+}
+
+// Is is errors.Is of the standard package
+func Is(err, target error) bool {
+	return stderrors.Is(err, target)
+}
+
+// As is errors.As of the standard package
+func As(err error, target interface{}) bool {
+	return stderrors.As(err, target)
 }
 
-func (s *xError) Error() string {
-	return fmt.Sprintf("%v %v", s.C, s.E)
+// Unwrap is errors.Unwrap of the standard package
+func Unwrap(err error) error {
+	return stderrors.Unwrap(err)
 }
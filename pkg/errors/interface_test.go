@@ -0,0 +1,129 @@
+package errors_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"tools/pkg/errors"
+)
+
+var (
+	errSentinel = errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("sentinel"))
+)
+
+// sliceError is not comparable
+type sliceError []string
+
+func (s sliceError) Error() string { return strings.Join(s, ", ") }
+
+func TestImmutable(t *testing.T) {
+	e := errSentinel.SetCode(errors.Fold)
+	if errSentinel.Code() != errors.Validate {
+		t.Errorf("sentinel is modified: %v", errSentinel)
+	}
+	if e.Code() != errors.Fold || e.Error() != "Fold sentinel" {
+		t.Errorf("not expected error: %v", e)
+	}
+}
+
+func TestIs(t *testing.T) {
+	wrapped := fmt.Errorf("outer: %w", errors.Wrap(errors.Map, errSentinel, "cannot execute"))
+	testcases := []struct {
+		Comment string
+		Err     error
+		Target  error
+		Result  bool
+	}{
+		{
+			Comment: "same",
+			Err:     errSentinel,
+			Target:  errSentinel,
+			Result:  true,
+		},
+		{
+			Comment: "wrapped",
+			Err:     wrapped,
+			Target:  errSentinel,
+			Result:  true,
+		},
+		{
+			Comment: "code",
+			Err:     wrapped,
+			Target:  errors.NewError().SetCode(errors.Map),
+			Result:  true,
+		},
+		{
+			Comment: "cause-code",
+			Err:     wrapped,
+			Target:  errors.NewError().SetCode(errors.Validate),
+			Result:  true,
+		},
+		{
+			Comment: "other-code",
+			Err:     wrapped,
+			Target:  errors.NewError().SetCode(errors.IO),
+		},
+		{
+			Comment: "other-cause",
+			Err:     errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("sentinel")),
+			Target:  errSentinel,
+		},
+		{
+			Comment: "not-comparable-cause",
+			Err:     errors.NewError().SetCode(errors.Validate).SetError(sliceError{"a"}),
+			Target:  errors.NewError().SetCode(errors.Validate).SetError(sliceError{"a"}),
+		},
+		{
+			Comment: "not-comparable-cause-code",
+			Err:     errors.NewError().SetCode(errors.Validate).SetError(sliceError{"a"}),
+			Target:  errors.NewError().SetCode(errors.Validate),
+			Result:  true,
+		},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.Comment, func(t *testing.T) {
+			if got := errors.Is(tt.Err, tt.Target); got != tt.Result {
+				t.Errorf("got %v", got)
+			}
+		})
+	}
+}
+
+func TestCodeOf(t *testing.T) {
+	testcases := []struct {
+		Comment string
+		Err     error
+		Result  errors.Code
+	}{
+		{Comment: "nil", Result: errors.Normal},
+		{Comment: "standard", Err: fmt.Errorf("x"), Result: errors.Unknown},
+		{Comment: "error", Err: errSentinel, Result: errors.Validate},
+		{Comment: "wrapped", Err: fmt.Errorf("x: %w", errors.Wrap(errors.Sort, errSentinel, "y")), Result: errors.Sort},
+	}
+	for _, tt := range testcases {
+		t.Run(tt.Comment, func(t *testing.T) {
+			if got := errors.CodeOf(tt.Err); got != tt.Result {
+				t.Errorf("got %v", got)
+			}
+		})
+	}
+}
+
+func TestStack(t *testing.T) {
+	if s := errSentinel.Stack(); s != "" {
+		t.Errorf("not expected stack: %s", s)
+	}
+	e := errSentinel.WithStack()
+	if s := e.Stack(); !strings.Contains(s, "errors_test.TestStack") {
+		t.Errorf("not expected stack: %s", s)
+	}
+	if s := fmt.Sprintf("%+v", e); !strings.HasPrefix(s, "Validate sentinel\n") || !strings.Contains(s, "TestStack") {
+		t.Errorf("not expected format: %s", s)
+	}
+
+	errors.EnableStack(true)
+	defer errors.EnableStack(false)
+	if s := errors.NewError().Stack(); !strings.Contains(s, "errors_test.TestStack") {
+		t.Errorf("not expected stack: %s", s)
+	}
+}
@@ -0,0 +1,217 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultMaxExamples = 5
+	maxPreviewLength   = 64
+)
+
+type (
+	// MultiError is errors grouped by code with counts and the first examples
+	MultiError struct {
+		mux         sync.Mutex
+		maxExamples int
+		count       int
+		groups      []*ErrorGroup
+	}
+
+	// ErrorGroup is errors of the same code
+	ErrorGroup struct {
+		Code     Code
+		Count    int
+		Examples []*ErrorExample
+	}
+
+	// ErrorExample is an error with context of the element
+	ErrorExample struct {
+		// Index is 1-based position of the element in the stream
+		Index int
+		// Line is line in the source, 0 if unknown
+		Line int
+		// Value is preview of the element, empty if unknown
+		Value string
+		Err   error
+	}
+
+	elementError struct {
+		err   error
+		value interface{}
+	}
+)
+
+// NewMultiError creates an empty MultiError keeps maxExamples examples per code.
+// default: 5 if maxExamples is not positive
+func NewMultiError(maxExamples int) *MultiError {
+	if maxExamples < 1 {
+		maxExamples = defaultMaxExamples
+	}
+	return &MultiError{
+		maxExamples: maxExamples,
+	}
+}
+
+// NewElementError marks err as an error of element v.
+// iterators may yield the next element after an element error
+func NewElementError(err error, v interface{}) error {
+	return &elementError{
+		err:   err,
+		value: v,
+	}
+}
+
+// IsElementError returns true if err is an error of an element, see NewElementError
+func IsElementError(err error) bool {
+	var e *elementError
+	return As(err, &e)
+}
+
+func (s *elementError) Error() string      { return s.err.Error() }
+func (s *elementError) Unwrap() error      { return s.err }
+func (s *elementError) Value() interface{} { return s.value }
+
+// Add adds err of index-th element.
+// line and value are taken from the chain of err, see NewElementError
+func (s *MultiError) Add(err error, index int) {
+	var (
+		code = CodeOf(err)
+		ex   = &ErrorExample{
+			Index: index,
+			Err:   err,
+		}
+		line interface{ Line() int }
+		v    interface{ Value() interface{} }
+	)
+	if As(err, &line) {
+		ex.Line = line.Line()
+	}
+	if As(err, &v) {
+		ex.Value = preview(v.Value())
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.count++
+	var g *ErrorGroup
+	for _, x := range s.groups {
+		if x.Code == code {
+			g = x
+			break
+		}
+	}
+	if g == nil {
+		g = &ErrorGroup{Code: code}
+		s.groups = append(s.groups, g)
+	}
+	g.Count++
+	if len(g.Examples) < s.maxExamples {
+		g.Examples = append(g.Examples, ex)
+	}
+}
+
+// Len returns number of errors
+func (s *MultiError) Len() int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.count
+}
+
+// Groups returns groups in the order of the first occurrence
+func (s *MultiError) Groups() []*ErrorGroup {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return append([]*ErrorGroup{}, s.groups...)
+}
+
+// Unwrap returns errors of examples
+func (s *MultiError) Unwrap() []error {
+	r := []error{}
+	for _, g := range s.Groups() {
+		for _, x := range g.Examples {
+			r = append(r, x.Err)
+		}
+	}
+	return r
+}
+
+// Error renders summary and examples per code, a line per example
+func (s *MultiError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d errors", s.Len())
+	for _, g := range s.Groups() {
+		fmt.Fprintf(&b, "\n%v: %d", g.Code, g.Count)
+		for _, x := range g.Examples {
+			fmt.Fprintf(&b, "\n\t%s", x)
+		}
+	}
+	return b.String()
+}
+
+func (s *ErrorExample) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "element %d", s.Index)
+	if s.Line > 0 {
+		fmt.Fprintf(&b, " line %d", s.Line)
+	}
+	if s.Value != "" {
+		fmt.Fprintf(&b, " value %q", s.Value)
+	}
+	fmt.Fprintf(&b, ": %v", s.Err)
+	return b.String()
+}
+
+// MarshalJSON renders count and groups
+func (s *MultiError) MarshalJSON() ([]byte, error) {
+	type (
+		example struct {
+			Index   int    `json:"index"`
+			Line    int    `json:"line,omitempty"`
+			Value   string `json:"value,omitempty"`
+			Message string `json:"message"`
+		}
+		group struct {
+			Code     string     `json:"code"`
+			Count    int        `json:"count"`
+			Examples []*example `json:"examples"`
+		}
+	)
+	var (
+		groups = s.Groups()
+		gs     = make([]*group, len(groups))
+	)
+	for i, g := range groups {
+		gs[i] = &group{
+			Code:     g.Code.String(),
+			Count:    g.Count,
+			Examples: make([]*example, len(g.Examples)),
+		}
+		for j, x := range g.Examples {
+			gs[i].Examples[j] = &example{
+				Index:   x.Index,
+				Line:    x.Line,
+				Value:   x.Value,
+				Message: x.Err.Error(),
+			}
+		}
+	}
+	return json.Marshal(struct {
+		Count  int      `json:"count"`
+		Groups []*group `json:"groups"`
+	}{
+		Count:  s.Len(),
+		Groups: gs,
+	})
+}
+
+func preview(v interface{}) string {
+	s := []rune(fmt.Sprintf("%v", v))
+	if len(s) > maxPreviewLength {
+		return string(s[:maxPreviewLength]) + "..."
+	}
+	return string(s)
+}
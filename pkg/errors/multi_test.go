@@ -0,0 +1,76 @@
+package errors_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"tools/pkg/errors"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type lineError struct {
+	line int
+}
+
+func (s *lineError) Error() string { return fmt.Sprintf("bad line %d", s.line) }
+func (s *lineError) Line() int     { return s.line }
+
+func TestMultiError(t *testing.T) {
+	m := errors.NewMultiError(2)
+	m.Add(errors.NewElementError(errors.NewError().SetCode(errors.Conversion).SetError(fmt.Errorf("not int")), "x"), 1)
+	m.Add(errors.NewError().SetCode(errors.Parse).SetError(&lineError{line: 7}), 2)
+	m.Add(errors.NewElementError(errors.NewError().SetCode(errors.Conversion).SetError(fmt.Errorf("not int")), strings.Repeat("y", 100)), 4)
+	m.Add(errors.NewError().SetCode(errors.Conversion).SetError(fmt.Errorf("not int")), 5)
+
+	if m.Len() != 4 {
+		t.Errorf("len %d", m.Len())
+	}
+	expected := strings.Join([]string{
+		"4 errors",
+		"Conversion: 3",
+		`	element 1 value "x": Conversion not int`,
+		`	element 4 value "` + strings.Repeat("y", 64) + `...": Conversion not int`,
+		"Parse: 1",
+		"	element 2 line 7: Parse bad line 7",
+	}, "\n")
+	if got := m.Error(); got != expected {
+		t.Errorf("  actual: %s\nexpected: %s", got, expected)
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual map[string]interface{}
+	if err := json.Unmarshal(b, &actual); err != nil {
+		t.Fatal(err)
+	}
+	groups := actual["groups"].([]interface{})
+	if actual["count"] != float64(4) || len(groups) != 2 {
+		t.Fatalf("not expected json: %s", b)
+	}
+	parse := map[string]interface{}{
+		"code":  "Parse",
+		"count": float64(1),
+		"examples": []interface{}{
+			map[string]interface{}{
+				"index":   float64(2),
+				"line":    float64(7),
+				"message": "Parse bad line 7",
+			},
+		},
+	}
+	if !cmp.Equal(groups[1], parse) {
+		t.Errorf("  actual: %v\nexpected: %v", groups[1], parse)
+	}
+
+	var le *lineError
+	if !errors.As(m, &le) || le.line != 7 {
+		t.Error("should unwrap examples")
+	}
+	if c := errors.CodeOf(m); c != errors.Conversion {
+		t.Errorf("not expected code: %v", c)
+	}
+}
@@ -0,0 +1,49 @@
+package errors
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+const maxStackDepth = 32
+
+var stackFlag int32
+
+// EnableStack makes NewError capture stack trace
+func EnableStack(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&stackFlag, v)
+}
+
+func stackEnabled() bool {
+	return atomic.LoadInt32(&stackFlag) == 1
+}
+
+// callers returns stack trace from the caller of skip levels above
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(skip+2, pcs)
+	return pcs[:n]
+}
+
+func formatStack(pcs []uintptr) string {
+	if len(pcs) == 0 {
+		return ""
+	}
+	var (
+		b      strings.Builder
+		frames = runtime.CallersFrames(pcs)
+	)
+	for {
+		f, more := frames.Next()
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+		if !more {
+			return b.String()
+		}
+	}
+}
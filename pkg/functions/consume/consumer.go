@@ -14,7 +14,7 @@ var (
 type (
 	// Consumer :: a
 	Consumer interface {
-		Apply(v interface{}) error
+		Apply(v interface{}, options ...reflection.Option) error
 	}
 
 	consumer struct {
@@ -41,10 +41,10 @@ func NewConsumer(f interface{}) (Consumer, errors.Error) {
 	}, nil
 }
 
-func (s *consumer) Apply(v interface{}) error {
-	av, err := reflection.ConvertShallow(v, s.t.In(0))
+func (s *consumer) Apply(v interface{}, options ...reflection.Option) error {
+	av, err := reflection.ConvertShallow(v, s.t.In(0), options...)
 	if err != nil {
-		return errors.NewError().SetCode(errors.Conversion).SetError(fmt.Errorf("invalid argument for consumer: %v", err))
+		return errors.NewError().SetCode(errors.Conversion).SetError(fmt.Errorf("invalid argument for consumer: %w", err))
 	}
 	s.v.Call([]reflect.Value{av})
 	return nil
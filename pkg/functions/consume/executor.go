@@ -1,17 +1,20 @@
 package consume
 
 import (
+	"tools/pkg/conv/reflection"
 	"tools/pkg/errors"
 	"tools/pkg/functions/executor"
 	"tools/pkg/functions/iterator"
+	"tools/pkg/log"
 )
 
 type (
 	// Executor is consume executor
 	Executor struct {
-		hooks executor.Hookable
-		f     Consumer
-		iter  iterator.Iterator
+		hooks      executor.Hookable
+		f          Consumer
+		iter       iterator.Iterator
+		conversion []reflection.Option
 	}
 	// Option changes option of Executor
 	Option func(*Executor)
@@ -24,6 +27,21 @@ func WithHook(ht executor.HookType, h interface{}) Option {
 	}
 }
 
+// WithLogger logs progress at debug level with field stage=consume
+func WithLogger(l log.Logger) Option {
+	return func(s *Executor) {
+		s.iter = executor.AddLogHooks(s.hooks, l.With("stage", "consume"), s.iter)
+	}
+}
+
+// WithConversion specifies options of conversion of arguments of functions and hooks
+func WithConversion(options ...reflection.Option) Option {
+	return func(s *Executor) {
+		s.conversion = append(s.conversion, options...)
+		s.hooks.SetConversion(s.conversion...)
+	}
+}
+
 func NewExecutor(f Consumer, iter iterator.Iterator, options ...Option) (*Executor, errors.Error) {
 	executor := &Executor{
 		hooks: executor.NewHookable(),
@@ -40,7 +58,7 @@ func (s *Executor) Execute() error {
 	s.hooks.Execute(executor.BeforeHook, s.iter)
 	for {
 		x, err := s.iter.Next()
-		if err == iterator.EOI {
+		if errors.Is(err, iterator.EOI) {
 			s.hooks.Execute(executor.AfterHook)
 			return nil
 		}
@@ -48,7 +66,7 @@ func (s *Executor) Execute() error {
 			return err
 		}
 		s.hooks.Execute(executor.RunningHook, x)
-		if err := s.f.Apply(x); err != nil {
+		if err := s.f.Apply(x, s.conversion...); err != nil {
 			return err
 		}
 		s.hooks.Execute(executor.RunningResultHook)
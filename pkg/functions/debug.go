@@ -0,0 +1,57 @@
+package functions
+
+import (
+	"os"
+	"strconv"
+	"sync/atomic"
+	"tools/pkg/functions/iterator"
+	"tools/pkg/functions/peek"
+	"tools/pkg/log"
+)
+
+const (
+	// DebugEnv turns debug taps on at start if true, e.g. FUNCTIONS_DEBUG=1, see SetDebugTap
+	DebugEnv = "FUNCTIONS_DEBUG"
+	// debugTapMaxLength truncates values of debug taps
+	debugTapMaxLength = 256
+)
+
+type (
+	debugTapHolder struct {
+		l log.Logger
+	}
+)
+
+var debugTap atomic.Value
+
+func init() {
+	if ok, _ := strconv.ParseBool(os.Getenv(DebugEnv)); ok {
+		SetDebugTap(log.NewLogger(log.WithLevel(log.Debug)))
+	}
+}
+
+// SetDebugTap logs elements yielded by every operator of streams created after this into l at debug level,
+// labeled by the operator. nil turns debug taps off
+func SetDebugTap(l log.Logger) {
+	debugTap.Store(&debugTapHolder{l: l})
+}
+
+func getDebugTap() log.Logger {
+	if x, ok := debugTap.Load().(*debugTapHolder); ok {
+		return x.l
+	}
+	return nil
+}
+
+// tap wraps iter by debug tap if enabled
+func tap(iter iterator.Iterator, label string) iterator.Iterator {
+	l := getDebugTap()
+	if l == nil {
+		return iter
+	}
+	debugExecutor, err := peek.NewDebugExecutor(l, label, iter, peek.WithMaxLength(debugTapMaxLength))
+	if err != nil {
+		return iter
+	}
+	return debugExecutor.Execute()
+}
@@ -13,10 +13,13 @@ type (
 		// Execute executes hooks of the hook type.
 		// executes functions that have appropriate size and types of arguments
 		Execute(ht HookType, args ...interface{})
+		// SetConversion specifies options of conversion of arguments
+		SetConversion(options ...reflection.Option) Hookable
 	}
 
 	hookable struct {
-		hooks map[HookType][]interface{}
+		hooks      map[HookType][]interface{}
+		conversion []reflection.Option
 	}
 )
 
@@ -53,6 +56,11 @@ func (s *hookable) GetHook(ht HookType) []interface{} {
 	return s.hooks[ht]
 }
 
+func (s *hookable) SetConversion(options ...reflection.Option) Hookable {
+	s.conversion = options
+	return s
+}
+
 func (s *hookable) Execute(ht HookType, args ...interface{}) {
 	for _, h := range s.GetHook(ht) {
 		s.execute(h, args...)
@@ -66,7 +74,7 @@ func (s *hookable) execute(h interface{}, args ...interface{}) {
 	}
 	vargs := make([]reflect.Value, len(args))
 	for i, a := range args {
-		v, err := reflection.ConvertShallow(a, t.In(i))
+		v, err := reflection.ConvertShallow(a, t.In(i), s.conversion...)
 		if err != nil {
 			return
 		}
@@ -0,0 +1,42 @@
+package executor
+
+import (
+	"sync"
+	"sync/atomic"
+	"tools/pkg/functions/iterator"
+	"tools/pkg/log"
+)
+
+type (
+	// counter counts elements pulled from iter
+	counter struct {
+		iter iterator.Iterator
+		n    int64
+	}
+)
+
+func (s *counter) Next() (interface{}, error) {
+	x, err := s.iter.Next()
+	if err == nil {
+		atomic.AddInt64(&s.n, 1)
+	}
+	return x, err
+}
+
+// AddLogHooks adds hooks log start, end and number of elements at debug level.
+// returns iter counts elements pulled from it, executor should pull elements from the returned one
+func AddLogHooks(h Hookable, l log.Logger, iter iterator.Iterator) iterator.Iterator {
+	var (
+		c    = &counter{iter: iter}
+		once sync.Once
+	)
+	h.AddHook(BeforeHook, func(interface{}) {
+		l.Debug("start")
+	})
+	h.AddHook(AfterHook, func() {
+		once.Do(func() {
+			l.Debug("end: %d elements", atomic.LoadInt64(&c.n))
+		})
+	})
+	return c
+}
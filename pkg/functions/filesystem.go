@@ -0,0 +1,312 @@
+package functions
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"tools/pkg/errors"
+	"tools/pkg/functions/iterator"
+	"tools/pkg/io/compress"
+	"tools/pkg/io/read"
+)
+
+type (
+	// FileEntry is a file yielded from filesystem sources
+	FileEntry interface {
+		Path() string
+		Info() os.FileInfo
+	}
+
+	fileEntry struct {
+		path string
+		info os.FileInfo
+	}
+
+	// FileLine is a line with its origin
+	FileLine interface {
+		// File is path of the file
+		File() string
+		// Line is 1-based line number in the file
+		Line() int
+		Bytes() []byte
+	}
+
+	fileLine struct {
+		file string
+		line int
+		b    []byte
+	}
+
+	// FileOption changes option of filesystem sources
+	FileOption func(*fileConfig)
+
+	fileConfig struct {
+		include     []string
+		exclude     []string
+		order       FileOrder
+		readOptions []read.Option
+	}
+)
+
+func (s *fileEntry) Path() string      { return s.path }
+func (s *fileEntry) Info() os.FileInfo { return s.info }
+
+func (s *fileLine) File() string  { return s.file }
+func (s *fileLine) Line() int     { return s.line }
+func (s *fileLine) Bytes() []byte { return s.b }
+
+//go:generate stringer -type=FileOrder -output generated.fileorder_string.go
+type FileOrder int
+
+const (
+	UnknownFileOrder FileOrder = iota
+	// FileOrderName sorts files by path
+	FileOrderName
+	// FileOrderModTime sorts files by modification time, older first
+	FileOrderModTime
+	// FileOrderSize sorts files by size, smaller first
+	FileOrderSize
+)
+
+// WithInclude yields only files whose base name matches any of patterns of filepath.Match
+func WithInclude(patterns ...string) FileOption {
+	return func(s *fileConfig) {
+		s.include = append(s.include, patterns...)
+	}
+}
+
+// WithExclude skips files and directories whose base name matches any of patterns of filepath.Match
+func WithExclude(patterns ...string) FileOption {
+	return func(s *fileConfig) {
+		s.exclude = append(s.exclude, patterns...)
+	}
+}
+
+// WithOrder specifies order of files.
+// default: FileOrderName
+func WithOrder(o FileOrder) FileOption {
+	return func(s *fileConfig) {
+		s.order = o
+	}
+}
+
+// WithReadOptions specifies options to split files into lines
+func WithReadOptions(options ...read.Option) FileOption {
+	return func(s *fileConfig) {
+		s.readOptions = append(s.readOptions, options...)
+	}
+}
+
+func newFileConfig(options ...FileOption) *fileConfig {
+	c := &fileConfig{
+		order: FileOrderName,
+	}
+	for _, opt := range options {
+		opt(c)
+	}
+	return c
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *fileConfig) isExcluded(path string) bool {
+	return matchAny(s.exclude, filepath.Base(path))
+}
+
+func (s *fileConfig) isIncluded(path string) bool {
+	return len(s.include) == 0 || matchAny(s.include, filepath.Base(path))
+}
+
+func (s *fileConfig) sort(entries []*fileEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		x, y := entries[i], entries[j]
+		switch s.order {
+		case FileOrderModTime:
+			return x.info.ModTime().Before(y.info.ModTime())
+		case FileOrderSize:
+			return x.info.Size() < y.info.Size()
+		default:
+			return x.path < y.path
+		}
+	})
+}
+
+func (s *fileConfig) newStream(entries []*fileEntry) Stream {
+	s.sort(entries)
+	r := make([]FileEntry, len(entries))
+	for i, x := range entries {
+		r[i] = x
+	}
+	return NewStream(iterator.MustNew(r))
+}
+
+// NewWalkSourceStream creates a stream yields FileEntry of regular files under root recursively
+func NewWalkSourceStream(root string, options ...FileOption) Stream {
+	var (
+		conf    = newFileConfig(options...)
+		entries = []*fileEntry{}
+	)
+	if err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != root && conf.isExcluded(path) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || !conf.isIncluded(path) {
+			return nil
+		}
+		entries = append(entries, &fileEntry{
+			path: path,
+			info: info,
+		})
+		return nil
+	}); err != nil {
+		return NewNilStream(errors.NewError().SetCode(errors.IO).SetError(err))
+	}
+	return conf.newStream(entries)
+}
+
+// NewGlobSourceStream creates a stream yields FileEntry of regular files matched by pattern of filepath.Glob
+func NewGlobSourceStream(pattern string, options ...FileOption) Stream {
+	conf := newFileConfig(options...)
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return NewNilStream(errors.NewError().SetCode(errors.Validate).SetError(err))
+	}
+	entries := []*fileEntry{}
+	for _, path := range paths {
+		if conf.isExcluded(path) || !conf.isIncluded(path) {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return NewNilStream(errors.NewError().SetCode(errors.IO).SetError(err))
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		entries = append(entries, &fileEntry{
+			path: path,
+			info: info,
+		})
+	}
+	return conf.newStream(entries)
+}
+
+// NewFileLinesSourceStream creates a stream yields FileLine of every line of files.
+// files is a stream of FileEntry or path string, files are read in the order of the stream.
+// files are filtered by WithInclude and WithExclude.
+// compressed files are decompressed transparently
+func NewFileLinesSourceStream(files Stream, options ...FileOption) Stream {
+	var (
+		conf  = newFileConfig(options...)
+		cur   *lineFile
+		iFunc func() (interface{}, error)
+	)
+	iFunc = func() (interface{}, error) {
+		for cur == nil {
+			x, err := files.Next()
+			if err != nil {
+				return nil, err
+			}
+			path, ok := pathOf(x)
+			if !ok {
+				return nil, errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("not a file: %v", x))
+			}
+			if conf.isExcluded(path) || !conf.isIncluded(path) {
+				continue
+			}
+			f, err := openLineFile(path, conf.readOptions)
+			if err != nil {
+				return nil, err
+			}
+			cur = f
+		}
+		x, err := cur.next()
+		if errors.Is(err, iterator.EOI) {
+			cur.close()
+			cur = nil
+			return iFunc()
+		}
+		if err != nil {
+			cur.close()
+			cur = nil
+			return nil, err
+		}
+		return x, nil
+	}
+	return NewStream(iterator.MustNew(iterator.Func(iFunc)))
+}
+
+func pathOf(x interface{}) (string, bool) {
+	switch x := x.(type) {
+	case FileEntry:
+		return x.Path(), true
+	case string:
+		return x, true
+	}
+	return "", false
+}
+
+type (
+	lineFile struct {
+		path string
+		file *os.File
+		r    interface{ Close() error }
+		iter read.Iterator
+		line int
+	}
+)
+
+func openLineFile(path string, options []read.Option) (*lineFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.NewError().SetCode(errors.IO).SetError(err)
+	}
+	r, err := compress.NewReader(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &lineFile{
+		path: path,
+		file: f,
+		r:    r,
+		iter: read.NewScannerIterator(r, options...),
+	}, nil
+}
+
+func (s *lineFile) next() (FileLine, error) {
+	b, err := s.iter.Next()
+	if errors.Is(err, read.EOI) {
+		return nil, iterator.EOI
+	}
+	if err != nil {
+		return nil, errors.Wrap(errors.CodeOf(err), err, s.path)
+	}
+	s.line++
+	c := make([]byte, len(b))
+	copy(c, b)
+	return &fileLine{
+		file: s.path,
+		line: s.line,
+		b:    c,
+	}, nil
+}
+
+func (s *lineFile) close() {
+	_ = s.r.Close()
+	_ = s.file.Close()
+}
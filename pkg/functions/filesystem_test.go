@@ -0,0 +1,188 @@
+package functions_test
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"tools/pkg/errors"
+	"tools/pkg/functions"
+	"tools/pkg/functions/iterator"
+	"tools/pkg/io/read"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Ext(name) == ".gz" {
+			w := gzip.NewWriter(f)
+			if _, err := w.Write([]byte(content)); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+		} else if _, err := f.WriteString(content); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFilesystemSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filesystem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestFiles(t, dir, map[string]string{
+		"a.log":          "a1\na2\n",
+		"b.txt":          "b1\n",
+		"sub/c.log.gz":   "c1\nc2\n",
+		"skip/d.log":     "d1\n",
+		"sub/e.log":      "",
+		"sub/f.log.keep": "f1\n",
+	})
+	rel := func(path string) string {
+		r, _ := filepath.Rel(dir, path)
+		return filepath.ToSlash(r)
+	}
+
+	testcases := []struct {
+		Comment string
+		Source  func() functions.Stream
+		Result  []string
+	}{
+		{
+			Comment: "walk",
+			Source: func() functions.Stream {
+				return functions.NewWalkSourceStream(dir)
+			},
+			Result: []string{"a.log", "b.txt", "skip/d.log", "sub/c.log.gz", "sub/e.log", "sub/f.log.keep"},
+		},
+		{
+			Comment: "walk-filter",
+			Source: func() functions.Stream {
+				return functions.NewWalkSourceStream(dir,
+					functions.WithInclude("*.log", "*.gz"),
+					functions.WithExclude("skip"),
+				)
+			},
+			Result: []string{"a.log", "sub/c.log.gz", "sub/e.log"},
+		},
+		{
+			Comment: "walk-size",
+			Source: func() functions.Stream {
+				return functions.NewWalkSourceStream(dir,
+					functions.WithInclude("*.log"),
+					functions.WithOrder(functions.FileOrderSize),
+				)
+			},
+			Result: []string{"sub/e.log", "skip/d.log", "a.log"},
+		},
+		{
+			Comment: "glob",
+			Source: func() functions.Stream {
+				return functions.NewGlobSourceStream(filepath.Join(dir, "*", "*.log*"), functions.WithExclude("*.keep"))
+			},
+			Result: []string{"skip/d.log", "sub/c.log.gz", "sub/e.log"},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.Comment, func(t *testing.T) {
+			result := []string{}
+			if err := tt.Source().Consume(func(x interface{}) {
+				result = append(result, rel(x.(functions.FileEntry).Path()))
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(result, tt.Result) {
+				t.Errorf("  actual: %q\nexpected: %q", result, tt.Result)
+			}
+		})
+	}
+
+	t.Run("lines", func(t *testing.T) {
+		st := functions.NewFileLinesSourceStream(functions.NewWalkSourceStream(dir,
+			functions.WithInclude("*.log", "*.gz"),
+		))
+		result := []string{}
+		if err := st.Consume(func(x interface{}) {
+			l := x.(functions.FileLine)
+			result = append(result, fmt.Sprintf("%s:%d:%s", rel(l.File()), l.Line(), l.Bytes()))
+		}); err != nil {
+			t.Fatal(err)
+		}
+		expected := []string{
+			"a.log:1:a1",
+			"a.log:2:a2",
+			"skip/d.log:1:d1",
+			"sub/c.log.gz:1:c1",
+			"sub/c.log.gz:2:c2",
+		}
+		if !cmp.Equal(result, expected) {
+			t.Errorf("  actual: %q\nexpected: %q", result, expected)
+		}
+	})
+
+	t.Run("lines-paths-filter", func(t *testing.T) {
+		paths := []string{
+			filepath.Join(dir, "a.log"),
+			filepath.Join(dir, "b.txt"),
+			filepath.Join(dir, "skip", "d.log"),
+		}
+		st := functions.NewFileLinesSourceStream(functions.NewStream(iterator.MustNew(paths)),
+			functions.WithInclude("*.log"),
+			functions.WithExclude("d.log"),
+		)
+		result := []string{}
+		if err := st.Consume(func(x interface{}) {
+			l := x.(functions.FileLine)
+			result = append(result, fmt.Sprintf("%s:%d:%s", rel(l.File()), l.Line(), l.Bytes()))
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if expected := []string{"a.log:1:a1", "a.log:2:a2"}; !cmp.Equal(result, expected) {
+			t.Errorf("  actual: %q\nexpected: %q", result, expected)
+		}
+	})
+
+	t.Run("lines-split-error", func(t *testing.T) {
+		path := filepath.Join(dir, "a.log")
+		st := functions.NewFileLinesSourceStream(functions.NewStream(iterator.MustNew([]string{path})),
+			functions.WithReadOptions(read.WithSplit(read.ScanDelimiter(nil))),
+		)
+		_, err := st.Next()
+		if !errors.Is(err, read.InvalidDelimiter) {
+			t.Fatalf("should be caused by invalid delimiter: %v", err)
+		}
+		if c := errors.CodeOf(err); c != errors.Validate {
+			t.Errorf("not expected code: %v", c)
+		}
+		if !strings.Contains(err.Error(), path) {
+			t.Errorf("should contain path: %v", err)
+		}
+	})
+
+	t.Run("lines-not-found", func(t *testing.T) {
+		st := functions.NewFileLinesSourceStream(functions.NewStream(iterator.MustNew([]string{filepath.Join(dir, "none")})))
+		if _, err := iterator.ToSlice(st); err == nil {
+			t.Error("should be error")
+		}
+	})
+}
@@ -1,17 +1,20 @@
 package filter
 
 import (
+	"tools/pkg/conv/reflection"
 	"tools/pkg/errors"
 	"tools/pkg/functions/executor"
 	"tools/pkg/functions/iterator"
+	"tools/pkg/log"
 )
 
 type (
 	// Executor is filter executor
 	Executor struct {
-		hooks executor.Hookable
-		f     Predicate
-		iter  iterator.Iterator
+		hooks      executor.Hookable
+		f          Predicate
+		iter       iterator.Iterator
+		conversion []reflection.Option
 	}
 	// Option changes option of Executor
 	Option func(*Executor)
@@ -24,6 +27,21 @@ func WithHook(ht executor.HookType, h interface{}) Option {
 	}
 }
 
+// WithLogger logs progress at debug level with field stage=filter
+func WithLogger(l log.Logger) Option {
+	return func(s *Executor) {
+		s.iter = executor.AddLogHooks(s.hooks, l.With("stage", "filter"), s.iter)
+	}
+}
+
+// WithConversion specifies options of conversion of arguments of functions and hooks
+func WithConversion(options ...reflection.Option) Option {
+	return func(s *Executor) {
+		s.conversion = append(s.conversion, options...)
+		s.hooks.SetConversion(s.conversion...)
+	}
+}
+
 func NewExecutor(f Predicate, iter iterator.Iterator, options ...Option) (*Executor, errors.Error) {
 	executor := &Executor{
 		hooks: executor.NewHookable(),
@@ -44,15 +62,15 @@ func (s *Executor) Execute() iterator.Iterator {
 	iFunc = func() (interface{}, error) {
 		x, err := s.iter.Next()
 		if err != nil {
-			if err == iterator.EOI {
+			if errors.Is(err, iterator.EOI) {
 				s.hooks.Execute(executor.AfterHook)
 			}
 			return nil, err
 		}
 		s.hooks.Execute(executor.RunningHook, x)
-		ret, err := s.f.Apply(x)
+		ret, err := s.f.Apply(x, s.conversion...)
 		if err != nil {
-			return nil, err
+			return nil, errors.NewElementError(err, x)
 		}
 		s.hooks.Execute(executor.RunningResultHook, ret)
 		if !ret {
@@ -14,7 +14,7 @@ var (
 type (
 	// Predicate :: a -> bool
 	Predicate interface {
-		Apply(v interface{}) (bool, error)
+		Apply(v interface{}, options ...reflection.Option) (bool, error)
 	}
 
 	predicate struct {
@@ -42,10 +42,10 @@ func NewPredicate(f interface{}) (Predicate, errors.Error) {
 	}, nil
 }
 
-func (s *predicate) Apply(v interface{}) (bool, error) {
-	av, err := reflection.ConvertShallow(v, s.t.In(0))
+func (s *predicate) Apply(v interface{}, options ...reflection.Option) (bool, error) {
+	av, err := reflection.ConvertShallow(v, s.t.In(0), options...)
 	if err != nil {
-		return false, errors.NewError().SetCode(errors.Conversion).SetError(fmt.Errorf("invalid argument for predicate: %v", err))
+		return false, errors.NewError().SetCode(errors.Conversion).SetError(fmt.Errorf("invalid argument for predicate: %w", err))
 	}
 	r := s.v.Call([]reflect.Value{av})
 	return r[0].Bool(), nil
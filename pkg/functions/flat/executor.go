@@ -1,23 +1,41 @@
 package flat
 
 import (
+	"fmt"
+	"reflect"
 	"tools/pkg/collections/stack"
+	"tools/pkg/conv/reflection"
 	"tools/pkg/errors"
 	"tools/pkg/functions/executor"
 	"tools/pkg/functions/iterator"
+	"tools/pkg/log"
 )
 
 type (
 	// Executor is flat executor
 	Executor struct {
-		hooks executor.Hookable
-		iter  iterator.Iterator
-		ft    Type
+		hooks         executor.Hookable
+		iter          iterator.Iterator
+		ft            Type
+		depth         int
+		hasDepth      bool
+		mapMode       MapMode
+		bytesAsScalar bool
+
+		conversion []reflection.Option
 	}
 	// Option changes option of Executor
 	Option func(*Executor)
 )
 
+var (
+	InvalidType    = errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("invalid type"))
+	InvalidDepth   = errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("depth must be positive"))
+	InvalidMapMode = errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("invalid map mode"))
+
+	bytesType = reflect.TypeOf([]byte{})
+)
+
 //go:generate stringer -type=Type -output generated.type_string.go
 type Type int
 
@@ -29,6 +47,22 @@ const (
 	TypePerfect
 )
 
+//go:generate stringer -type=MapMode -output generated.mapmode_string.go
+type MapMode int
+
+const (
+	MapUnknown MapMode = iota
+	// MapKV yields iterator.KV from map
+	MapKV
+	// MapKeys yields keys from map
+	MapKeys
+	// MapValues yields values from map
+	MapValues
+)
+
+// unlimitedDepth flattens recursively
+const unlimitedDepth = -1
+
 // WithType specifies flat function type
 func WithType(ft Type) Option {
 	return func(s *Executor) {
@@ -36,6 +70,30 @@ func WithType(ft Type) Option {
 	}
 }
 
+// WithDepth flattens n levels, overrides WithType.
+// n must be positive
+func WithDepth(n int) Option {
+	return func(s *Executor) {
+		s.depth = n
+		s.hasDepth = true
+	}
+}
+
+// WithMapMode specifies what elements map yields.
+// default: MapKV
+func WithMapMode(m MapMode) Option {
+	return func(s *Executor) {
+		s.mapMode = m
+	}
+}
+
+// WithBytesAsScalar treats []byte as a scalar, not as bytes
+func WithBytesAsScalar() Option {
+	return func(s *Executor) {
+		s.bytesAsScalar = true
+	}
+}
+
 // WithHook add hook
 func WithHook(ht executor.HookType, h interface{}) Option {
 	return func(s *Executor) {
@@ -43,90 +101,116 @@ func WithHook(ht executor.HookType, h interface{}) Option {
 	}
 }
 
+// WithLogger logs progress at debug level with field stage=flat
+func WithLogger(l log.Logger) Option {
+	return func(s *Executor) {
+		s.iter = executor.AddLogHooks(s.hooks, l.With("stage", "flat"), s.iter)
+	}
+}
+
+// WithConversion specifies options of conversion of arguments of hooks
+func WithConversion(options ...reflection.Option) Option {
+	return func(s *Executor) {
+		s.conversion = append(s.conversion, options...)
+		s.hooks.SetConversion(s.conversion...)
+	}
+}
+
 func NewExecutor(iter iterator.Iterator, options ...Option) (*Executor, errors.Error) {
 	executor := &Executor{
-		hooks: executor.NewHookable(),
-		iter:  iter,
-		ft:    TypeSimple,
+		hooks:   executor.NewHookable(),
+		iter:    iter,
+		ft:      TypeSimple,
+		mapMode: MapKV,
 	}
 	for _, opt := range options {
 		opt(executor)
 	}
+	switch {
+	case executor.hasDepth && executor.depth < 1:
+		return nil, InvalidDepth
+	case executor.hasDepth:
+	case executor.ft == TypeSimple:
+		executor.depth = 1
+	case executor.ft == TypePerfect:
+		executor.depth = unlimitedDepth
+	default:
+		return nil, InvalidType
+	}
+	switch executor.mapMode {
+	case MapKV, MapKeys, MapValues:
+	default:
+		return nil, InvalidMapMode
+	}
 	return executor, nil
 }
 
-func (s *Executor) Execute() iterator.Iterator {
-	switch s.ft {
-	case TypePerfect:
-		return s.executePerfect()
-	case TypeSimple:
-		return s.executeSimple()
+type (
+	frame struct {
+		iter  iterator.Iterator
+		level int
 	}
-	return nil
-}
+)
 
-// executePerfect flat an iterator recursively.
-// this yields elements that cannot be an iterator
-func (s *Executor) executePerfect() iterator.Iterator {
+// Execute flat an iterator up to the depth.
+// this yields elements that cannot be an iterator or are at the depth
+func (s *Executor) Execute() iterator.Iterator {
 	s.hooks.Execute(executor.BeforeHook, s.iter)
 	var (
 		stk   = stack.New()
 		iFunc func() (interface{}, error)
 	)
-	stk.Push(s.iter)
+	stk.Push(&frame{iter: s.iter})
 	iFunc = func() (interface{}, error) {
-		top, err := stk.Peep()
+		p, err := stk.Peep()
 		if err != nil {
 			s.hooks.Execute(executor.AfterHook)
 			return nil, iterator.EOI
 		}
-		x, err := top.(iterator.Iterator).Next()
-		if err == iterator.EOI {
+		top := p.(*frame)
+		x, err := top.iter.Next()
+		if errors.Is(err, iterator.EOI) {
 			_, _ = stk.Pop()
 			return iFunc()
 		}
 		if err != nil {
 			return nil, err
 		}
-		if iterator.CanBeSingleValueIterator(x) {
-			s.hooks.Execute(executor.RunningHook, x)
-			return x, nil
+		if (s.depth == unlimitedDepth || top.level < s.depth) && s.canFlat(x) {
+			stk.Push(&frame{
+				iter:  s.newIterator(x),
+				level: top.level + 1,
+			})
+			return iFunc()
 		}
-		stk.Push(iterator.MustNew(x))
-		return iFunc()
+		s.hooks.Execute(executor.RunningHook, x)
+		return x, nil
 	}
 	return iterator.MustNew(iterator.Func(iFunc))
 }
 
-// executeSimple flat an iterator 1 level
-func (s *Executor) executeSimple() iterator.Iterator {
-	s.hooks.Execute(executor.BeforeHook, s.iter)
+func (s *Executor) canFlat(x interface{}) bool {
+	if s.bytesAsScalar && reflect.TypeOf(x) == bytesType {
+		return false
+	}
+	return !iterator.CanBeSingleValueIterator(x)
+}
+
+func (s *Executor) newIterator(x interface{}) iterator.Iterator {
+	if x == nil || s.mapMode == MapKV || reflect.TypeOf(x).Kind() != reflect.Map {
+		return iterator.MustNew(x)
+	}
 	var (
-		top   iterator.Iterator
-		iFunc func() (interface{}, error)
+		iter = reflect.ValueOf(x).MapRange()
+		keys = s.mapMode == MapKeys
 	)
-	iFunc = func() (interface{}, error) {
-		if top == nil {
-			elem, err := s.iter.Next()
-			if err != nil {
-				if err == iterator.EOI {
-					s.hooks.Execute(executor.AfterHook)
-				}
-				return nil, err
-			}
-			top = iterator.MustNew(elem)
-		}
-
-		x, err := top.Next()
-		if err == iterator.EOI {
-			top = nil
-			return iFunc()
+	return iterator.MustNew(iterator.Func(func() (interface{}, error) {
+		if !iter.Next() {
+			return nil, iterator.EOI
 		}
-		if err != nil {
-			return nil, err
+		if keys {
+			return iter.Key().Interface(), nil
 		}
-		s.hooks.Execute(executor.RunningHook, x)
-		return x, nil
-	}
-	return iterator.MustNew(iterator.Func(iFunc))
+		return iter.Value().Interface(), nil
+	}))
 }
@@ -0,0 +1,293 @@
+/*
+Package agg provides ready-made aggregators for Stream.Fold.
+
+aggregators are left aggregators :: b -> a -> b, Stream.Fold folds them by Foldl in constant stack.
+elements are numbers of any kind unless noted,
+aggregators return error with code errors.Conversion on non-number elements.
+
+Sketches are approximate summaries in bounded memory, fold them by Merge,
+a perfect aggregator works with any fold type including fold.TypeParallel.
+*/
+package agg
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"tools/pkg/conv/reflection"
+	"tools/pkg/errors"
+)
+
+var (
+	InvalidAggregator = errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("aggregator must be b -> a -> b"))
+	InvalidName       = errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("invalid field name"))
+)
+
+// toFloat converts number of any kind into float64
+func toFloat(x interface{}) (float64, errors.Error) {
+	v := reflect.ValueOf(x)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	}
+	return 0, errors.NewError().SetCode(errors.Conversion).SetError(fmt.Errorf("not a number: %v", x))
+}
+
+// Count counts elements of any type
+//
+// aggregator :: int -> a -> int
+func Count() interface{} {
+	return func(acc int, _ interface{}) int {
+		return acc + 1
+	}
+}
+
+// Sum sums elements
+//
+// aggregator :: float64 -> a -> float64
+func Sum() interface{} {
+	return func(acc float64, x interface{}) (float64, error) {
+		f, err := toFloat(x)
+		if err != nil {
+			return 0, err
+		}
+		return acc + f, nil
+	}
+}
+
+type (
+	// Extremum is the least or the greatest element
+	Extremum struct {
+		// Value is the element as is, nil if no elements
+		Value interface{}
+	}
+)
+
+// extremum keeps the first element among equal elements, replaces acc if replace returns true
+func extremum(replace func(x, acc float64) bool) interface{} {
+	return func(acc Extremum, x interface{}) (Extremum, error) {
+		f, err := toFloat(x)
+		if err != nil {
+			return Extremum{}, err
+		}
+		if acc.Value == nil {
+			return Extremum{Value: x}, nil
+		}
+		a, err := toFloat(acc.Value)
+		if err != nil {
+			return Extremum{}, err
+		}
+		if replace(f, a) {
+			return Extremum{Value: x}, nil
+		}
+		return acc, nil
+	}
+}
+
+// Min finds the least element, the first one among equal elements
+//
+// aggregator :: Extremum -> a -> Extremum
+func Min() interface{} {
+	return extremum(func(x, acc float64) bool { return x < acc })
+}
+
+// Max finds the greatest element, the first one among equal elements
+//
+// aggregator :: Extremum -> a -> Extremum
+func Max() interface{} {
+	return extremum(func(x, acc float64) bool { return x > acc })
+}
+
+type (
+	// Moments is mean and variance by Welford's algorithm
+	Moments struct {
+		N    int
+		Mean float64
+		// M2 is sum of squares of differences from the mean
+		M2 float64
+	}
+)
+
+// Variance returns population variance, NaN if no elements
+func (s Moments) Variance() float64 {
+	if s.N == 0 {
+		return math.NaN()
+	}
+	return s.M2 / float64(s.N)
+}
+
+// SampleVariance returns unbiased sample variance, NaN if less than 2 elements
+func (s Moments) SampleVariance() float64 {
+	if s.N < 2 {
+		return math.NaN()
+	}
+	return s.M2 / float64(s.N-1)
+}
+
+// StdDev returns population standard deviation
+func (s Moments) StdDev() float64 {
+	return math.Sqrt(s.Variance())
+}
+
+// Mean computes mean and variance
+//
+// aggregator :: Moments -> a -> Moments
+func Mean() interface{} {
+	return func(acc Moments, x interface{}) (Moments, error) {
+		f, err := toFloat(x)
+		if err != nil {
+			return Moments{}, err
+		}
+		acc.N++
+		d := f - acc.Mean
+		acc.Mean += d / float64(acc.N)
+		acc.M2 += d * (f - acc.Mean)
+		return acc, nil
+	}
+}
+
+type (
+	// Histogram is counts of elements per bucket
+	Histogram struct {
+		// Bounds are sorted lower bounds of buckets except the first one
+		Bounds []float64
+		// Counts has len(Bounds)+1 buckets, i-th bucket is [Bounds[i-1], Bounds[i])
+		Counts []int
+	}
+)
+
+// NewHistogram counts elements into fixed buckets by bounds
+//
+// aggregator :: Histogram -> a -> Histogram
+func NewHistogram(bounds ...float64) interface{} {
+	bs := append([]float64{}, bounds...)
+	sort.Float64s(bs)
+	return func(acc Histogram, x interface{}) (Histogram, error) {
+		f, err := toFloat(x)
+		if err != nil {
+			return Histogram{}, err
+		}
+		if acc.Counts == nil {
+			acc.Bounds = bs
+			acc.Counts = make([]int, len(bs)+1)
+		}
+		acc.Counts[sort.Search(len(bs), func(i int) bool { return bs[i] > f })]++
+		return acc, nil
+	}
+}
+
+// Frequencies counts elements of any comparable type
+//
+// aggregator :: map[interface{}]int -> a -> map[interface{}]int
+func Frequencies() interface{} {
+	return func(acc map[interface{}]int, x interface{}) (map[interface{}]int, error) {
+		if x != nil && !reflect.TypeOf(x).Comparable() {
+			return nil, errors.NewError().SetCode(errors.Conversion).SetError(fmt.Errorf("not comparable: %v", x))
+		}
+		if acc == nil {
+			acc = map[interface{}]int{}
+		}
+		acc[x]++
+		return acc, nil
+	}
+}
+
+type (
+	// Field is an aggregator with name for Combine
+	Field struct {
+		name string
+		f    interface{}
+	}
+)
+
+// Named names aggregator f, name should be an exported field name
+func Named(name string, f interface{}) *Field {
+	return &Field{
+		name: name,
+		f:    f,
+	}
+}
+
+// Combine runs left aggregators in a single pass.
+// accumulator is a struct has a field per aggregator named by Field,
+// convert the result into your struct by Stream.As
+//
+// aggregator :: struct -> a -> struct
+func Combine(fields ...*Field) (interface{}, errors.Error) {
+	var (
+		errorType = reflect.TypeOf((*error)(nil)).Elem()
+		sfs       = make([]reflect.StructField, len(fields))
+		funcs     = make([]reflect.Value, len(fields))
+	)
+	for i, f := range fields {
+		t := reflect.TypeOf(f.f)
+		if t == nil || t.Kind() != reflect.Func || t.NumIn() != 2 || t.In(0) != t.Out(0) ||
+			!(t.NumOut() == 1 || t.NumOut() == 2 && t.Out(1) == errorType) {
+			return nil, InvalidAggregator
+		}
+		if f.name == "" || strings.ToUpper(f.name[:1]) != f.name[:1] {
+			return nil, InvalidName
+		}
+		sfs[i] = reflect.StructField{
+			Name: f.name,
+			Type: t.Out(0),
+		}
+		funcs[i] = reflect.ValueOf(f.f)
+	}
+	var (
+		st          reflect.Type
+		emptyFace   = reflect.TypeOf((*interface{})(nil)).Elem()
+		constructed = func() (err errors.Error) {
+			defer func() {
+				if x := recover(); x != nil {
+					err = errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("cannot combine: %v", x))
+				}
+			}()
+			st = reflect.StructOf(sfs)
+			return nil
+		}()
+	)
+	if constructed != nil {
+		return nil, constructed
+	}
+	ft := reflect.FuncOf([]reflect.Type{st, emptyFace}, []reflect.Type{st, errorType}, false)
+	return reflect.MakeFunc(ft, func(args []reflect.Value) []reflect.Value {
+		var (
+			acc = reflect.New(st).Elem()
+			x   = args[1]
+		)
+		acc.Set(args[0])
+		for i, f := range funcs {
+			in, err := reflection.ConvertShallow(x.Interface(), f.Type().In(1))
+			if err != nil {
+				e := reflect.New(errorType).Elem()
+				e.Set(reflect.ValueOf(errors.Wrap(errors.Conversion, err, fmt.Sprintf("invalid argument for %s", sfs[i].Name))))
+				return []reflect.Value{reflect.Zero(st), e}
+			}
+			r := f.Call([]reflect.Value{acc.Field(i), in})
+			if len(r) == 2 && !r[1].IsNil() {
+				return []reflect.Value{reflect.Zero(st), r[1]}
+			}
+			acc.Field(i).Set(r[0])
+		}
+		return []reflect.Value{acc, reflect.Zero(errorType)}
+	}).Interface(), nil
+}
+
+// MustCombine is Combine but panics on error
+func MustCombine(fields ...*Field) interface{} {
+	f, err := Combine(fields...)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
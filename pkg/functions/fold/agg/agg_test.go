@@ -0,0 +1,198 @@
+package agg_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"tools/pkg/functions"
+	"tools/pkg/functions/fold/agg"
+	"tools/pkg/functions/iterator"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type (
+	aggTestcase struct {
+		Comment    string
+		Data       interface{}
+		Aggregator interface{}
+		Result     interface{}
+		Error      string
+	}
+)
+
+func (s *aggTestcase) Test(t *testing.T) {
+	st := functions.NewStream(iterator.MustNew(s.Data)).Fold(s.Aggregator)
+	var err error
+	actual, err := iterator.ToSlice(st)
+	if err == nil {
+		err = st.Err()
+	}
+	if s.Error != "" {
+		if err == nil || !strings.Contains(err.Error(), s.Error) {
+			t.Errorf("not expected error: %v", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(actual, []interface{}{s.Result}) {
+		t.Errorf("not expected result:\n  actual(%#v)\nexpected(%#v)", actual, s.Result)
+	}
+}
+
+func TestAggregators(t *testing.T) {
+	testcases := []*aggTestcase{
+		{
+			Comment:    "count",
+			Data:       []string{"a", "b", "c"},
+			Aggregator: agg.Count(),
+			Result:     3,
+		},
+		{
+			Comment:    "sum",
+			Data:       []interface{}{1, uint8(2), 1.5},
+			Aggregator: agg.Sum(),
+			Result:     4.5,
+		},
+		{
+			Comment:    "sum-not-number",
+			Data:       []interface{}{1, "x"},
+			Aggregator: agg.Sum(),
+			Error:      "not a number: x",
+		},
+		{
+			Comment:    "min",
+			Data:       []int{3, 1, 2},
+			Aggregator: agg.Min(),
+			Result:     agg.Extremum{Value: 1},
+		},
+		{
+			Comment:    "max",
+			Data:       []float64{3, 1, 2},
+			Aggregator: agg.Max(),
+			Result:     agg.Extremum{Value: float64(3)},
+		},
+		{
+			Comment:    "max-empty",
+			Data:       []float64{},
+			Aggregator: agg.Max(),
+			Result:     agg.Extremum{},
+		},
+		{
+			Comment:    "min-first-of-equals",
+			Data:       []interface{}{3, 1, 1.0},
+			Aggregator: agg.Min(),
+			Result:     agg.Extremum{Value: 1},
+		},
+		{
+			Comment:    "max-first-of-equals",
+			Data:       []interface{}{3.0, 1, 3},
+			Aggregator: agg.Max(),
+			Result:     agg.Extremum{Value: 3.0},
+		},
+		{
+			Comment:    "max-not-number",
+			Data:       []interface{}{1, "x"},
+			Aggregator: agg.Max(),
+			Error:      "not a number: x",
+		},
+		{
+			Comment:    "histogram",
+			Data:       []int{-1, 0, 5, 10, 11, 100},
+			Aggregator: agg.NewHistogram(10, 0),
+			Result: agg.Histogram{
+				Bounds: []float64{0, 10},
+				Counts: []int{1, 2, 3},
+			},
+		},
+		{
+			Comment:    "frequencies",
+			Data:       []string{"a", "b", "a"},
+			Aggregator: agg.Frequencies(),
+			Result:     map[interface{}]int{"a": 2, "b": 1},
+		},
+		{
+			Comment:    "frequencies-not-comparable",
+			Data:       []interface{}{"a", []int{1}},
+			Aggregator: agg.Frequencies(),
+			Error:      "not comparable: [1]",
+		},
+		{
+			Comment:    "histogram-not-number",
+			Data:       []interface{}{1, "x"},
+			Aggregator: agg.NewHistogram(0),
+			Error:      "not a number: x",
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.Comment, func(t *testing.T) {
+			tt.Test(t)
+		})
+	}
+}
+
+func TestMean(t *testing.T) {
+	var r []agg.Moments
+	if err := functions.NewStream(iterator.MustNew([]int{2, 4, 4, 4, 5, 5, 7, 9})).Fold(agg.Mean()).As(&r); err != nil {
+		t.Fatal(err)
+	}
+	m := r[0]
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+	if m.N != 8 || !near(m.Mean, 5) || !near(m.Variance(), 4) || !near(m.StdDev(), 2) {
+		t.Errorf("got %#v", m)
+	}
+	if v := (agg.Moments{}).Variance(); !math.IsNaN(v) {
+		t.Errorf("got %v", v)
+	}
+}
+
+func TestLargeStream(t *testing.T) {
+	const n = 1000000
+	var r []int
+	if err := functions.NewStream(iterator.NewRangeIteratorBuilder().Stop(n).Build()).Fold(agg.Count()).As(&r); err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(r, []int{n}) {
+		t.Errorf("got %v", r)
+	}
+}
+
+func TestCombine(t *testing.T) {
+	type Stats struct {
+		Count int
+		Sum   float64
+		Max   agg.Extremum
+	}
+
+	f, err := agg.Combine(
+		agg.Named("Count", agg.Count()),
+		agg.Named("Sum", agg.Sum()),
+		agg.Named("Max", agg.Max()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r []Stats
+	if err := functions.NewStream(iterator.MustNew([]int{3, 1, 2})).Fold(f).As(&r); err != nil {
+		t.Fatal(err)
+	}
+	expected := []Stats{{Count: 3, Sum: 6, Max: agg.Extremum{Value: 3}}}
+	if !cmp.Equal(r, expected) {
+		t.Errorf("  actual: %#v\nexpected: %#v", r, expected)
+	}
+
+	if err := functions.NewStream(iterator.MustNew([]interface{}{3, "x"})).Fold(f).Err(); err == nil || !strings.Contains(err.Error(), "not a number: x") {
+		t.Errorf("not expected error: %v", err)
+	}
+
+	if _, err := agg.Combine(agg.Named("count", agg.Count())); err == nil {
+		t.Error("should be error for unexported name")
+	}
+	right := func(_ interface{}, acc int) int { return acc + 1 }
+	if _, err := agg.Combine(agg.Named("Count", right)); err == nil {
+		t.Error("should be error for right aggregator")
+	}
+}
@@ -0,0 +1,233 @@
+package agg
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"tools/pkg/errors"
+)
+
+type (
+	// CountMinSketch estimates frequencies of elements, never underestimates.
+	// overestimate is at most N * e / Width with probability 1 - exp(-Depth)
+	CountMinSketch struct {
+		ownership
+		Width  int        `json:"width"`
+		Depth  int        `json:"depth"`
+		Counts [][]uint64 `json:"counts"`
+		// N is number of elements
+		N uint64 `json:"n"`
+		// K is capacity of Hitters
+		K int `json:"k"`
+		// Hitters are K most frequent elements by estimate, in heap order, see TopHitters.
+		// numbers are stored as float64 to be the same after JSON round trip
+		Hitters []Hitter `json:"hitters"`
+		top     *hitterHeap
+	}
+
+	// Hitter is a frequent element
+	Hitter struct {
+		Value interface{} `json:"value"`
+		Count uint64      `json:"count"`
+	}
+)
+
+// NewCountMinSketch creates an empty CountMinSketch tracks k heavy hitters
+func NewCountMinSketch(width, depth, k int) (*CountMinSketch, errors.Error) {
+	if width < 1 || depth < 1 || k < 0 {
+		return nil, errors.NewError().SetCode(errors.Validate).SetError(
+			fmt.Errorf("invalid count-min sketch: width %d, depth %d, k %d", width, depth, k))
+	}
+	s := &CountMinSketch{
+		Width:  width,
+		Depth:  depth,
+		Counts: make([][]uint64, depth),
+		K:      k,
+	}
+	for i := range s.Counts {
+		s.Counts[i] = make([]uint64, width)
+	}
+	return s, nil
+}
+
+// indexes returns column of the hash per row by double hashing
+func (s *CountMinSketch) indexes(h uint64) []int {
+	var (
+		h1, h2 = h & 0xffffffff, h >> 32
+		r      = make([]int, s.Depth)
+	)
+	for i := range r {
+		r[i] = int((h1 + uint64(i)*h2) % uint64(s.Width))
+	}
+	return r
+}
+
+// Add adds an element
+func (s *CountMinSketch) Add(x interface{}) errors.Error {
+	var (
+		h = hash64(x)
+		c uint64
+	)
+	for i, j := range s.indexes(h) {
+		s.Counts[i][j]++
+		if n := s.Counts[i][j]; i == 0 || n < c {
+			c = n
+		}
+	}
+	s.N++
+	s.track(h, x, c)
+	return nil
+}
+
+// Count returns estimated frequency of the element
+func (s *CountMinSketch) Count(x interface{}) uint64 {
+	return s.count(hash64(x))
+}
+
+func (s *CountMinSketch) count(h uint64) uint64 {
+	var r uint64
+	for i, j := range s.indexes(h) {
+		if c := s.Counts[i][j]; i == 0 || c < r {
+			r = c
+		}
+	}
+	return r
+}
+
+// TopHitters returns hitters in descending order of count
+func (s *CountMinSketch) TopHitters() []Hitter {
+	r := append([]Hitter{}, s.Hitters...)
+	sort.SliceStable(r, func(i, j int) bool { return r[i].Count > r[j].Count })
+	return r
+}
+
+// hitters returns Hitters as a min-heap, builds index of it if not yet, e.g. decoded from JSON
+func (s *CountMinSketch) hitters() *hitterHeap {
+	if s.top == nil {
+		s.top = &hitterHeap{
+			s:      s,
+			hashes: make([]uint64, len(s.Hitters)),
+			pos:    make(map[uint64]int, len(s.Hitters)),
+		}
+		for i, x := range s.Hitters {
+			s.Hitters[i].Value = canonical(x.Value)
+			s.top.hashes[i] = hash64(x.Value)
+			s.top.pos[s.top.hashes[i]] = i
+		}
+		heap.Init(s.top)
+	}
+	return s.top
+}
+
+// track updates hitters with the element of hash h and estimated count c
+func (s *CountMinSketch) track(h uint64, x interface{}, c uint64) {
+	if s.K == 0 {
+		return
+	}
+	top := s.hitters()
+	if i, ok := top.pos[h]; ok {
+		s.Hitters[i].Count = c
+		heap.Fix(top, i)
+		return
+	}
+	if len(s.Hitters) < s.K {
+		heap.Push(top, &hitterEntry{hash: h, hitter: Hitter{Value: canonical(x), Count: c}})
+		return
+	}
+	if c > s.Hitters[0].Count {
+		delete(top.pos, top.hashes[0])
+		s.Hitters[0] = Hitter{Value: canonical(x), Count: c}
+		top.hashes[0] = h
+		top.pos[h] = 0
+		heap.Fix(top, 0)
+	}
+}
+
+type (
+	// hitterHeap is a min-heap by count on Hitters of the sketch,
+	// hashes are parallel to Hitters, pos is index of Hitters by hash
+	hitterHeap struct {
+		s      *CountMinSketch
+		hashes []uint64
+		pos    map[uint64]int
+	}
+
+	hitterEntry struct {
+		hitter Hitter
+		hash   uint64
+	}
+)
+
+func (s *hitterHeap) Len() int           { return len(s.s.Hitters) }
+func (s *hitterHeap) Less(i, j int) bool { return s.s.Hitters[i].Count < s.s.Hitters[j].Count }
+func (s *hitterHeap) Swap(i, j int) {
+	s.s.Hitters[i], s.s.Hitters[j] = s.s.Hitters[j], s.s.Hitters[i]
+	s.hashes[i], s.hashes[j] = s.hashes[j], s.hashes[i]
+	s.pos[s.hashes[i]] = i
+	s.pos[s.hashes[j]] = j
+}
+func (s *hitterHeap) Push(x interface{}) {
+	e := x.(*hitterEntry)
+	s.pos[e.hash] = len(s.hashes)
+	s.s.Hitters = append(s.s.Hitters, e.hitter)
+	s.hashes = append(s.hashes, e.hash)
+}
+func (s *hitterHeap) Pop() interface{} {
+	n := len(s.hashes) - 1
+	e := &hitterEntry{hitter: s.s.Hitters[n], hash: s.hashes[n]}
+	delete(s.pos, e.hash)
+	s.s.Hitters = s.s.Hitters[:n]
+	s.hashes = s.hashes[:n]
+	return e
+}
+
+// Merge merges other CountMinSketch with the same width, depth and k
+func (s *CountMinSketch) Merge(other Sketch) errors.Error {
+	o, ok := other.(*CountMinSketch)
+	if !ok {
+		return newMergeError("cannot merge %T into %T", other, s)
+	}
+	if o.Width != s.Width || o.Depth != s.Depth || o.K != s.K || len(o.Counts) != len(s.Counts) {
+		return newMergeError("cannot merge count-min sketch of %dx%d (k %d) into %dx%d (k %d)",
+			o.Width, o.Depth, o.K, s.Width, s.Depth, s.K)
+	}
+	for i, row := range o.Counts {
+		if len(row) != len(s.Counts[i]) {
+			return newMergeError("invalid count-min sketch row %d", i)
+		}
+		for j, c := range row {
+			s.Counts[i][j] += c
+		}
+	}
+	s.N += o.N
+	if s.K == 0 {
+		return nil
+	}
+	// counts of all hitters may change, selects top k again
+	var (
+		seen    = map[uint64]bool{}
+		hitters = make([]Hitter, 0, len(s.Hitters)+len(o.Hitters))
+	)
+	for _, x := range append(append([]Hitter{}, s.Hitters...), o.Hitters...) {
+		v := canonical(x.Value)
+		h := hash64(v)
+		if seen[h] {
+			continue
+		}
+		seen[h] = true
+		hitters = append(hitters, Hitter{Value: v, Count: s.count(h)})
+	}
+	sort.SliceStable(hitters, func(i, j int) bool { return hitters[i].Count > hitters[j].Count })
+	if len(hitters) > s.K {
+		hitters = hitters[:s.K]
+	}
+	s.Hitters = hitters
+	s.top = nil
+	return nil
+}
+
+// Empty returns an empty CountMinSketch with the same parameters
+func (s *CountMinSketch) Empty() Sketch {
+	r, _ := NewCountMinSketch(s.Width, s.Depth, s.K)
+	return r
+}
@@ -0,0 +1,106 @@
+package agg
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"tools/pkg/errors"
+)
+
+const (
+	MinPrecision = 4
+	MaxPrecision = 18
+)
+
+type (
+	// HyperLogLog estimates number of distinct elements.
+	// standard error is about 1.04 / sqrt(2^Precision)
+	HyperLogLog struct {
+		ownership
+		Precision int     `json:"precision"`
+		Registers []uint8 `json:"registers"`
+	}
+)
+
+// NewHyperLogLog creates an empty HyperLogLog with 2^precision registers
+func NewHyperLogLog(precision int) (*HyperLogLog, errors.Error) {
+	if precision < MinPrecision || precision > MaxPrecision {
+		return nil, errors.NewError().SetCode(errors.Validate).SetError(
+			fmt.Errorf("precision must be in [%d, %d]: %d", MinPrecision, MaxPrecision, precision))
+	}
+	return &HyperLogLog{
+		Precision: precision,
+		Registers: make([]uint8, 1<<uint(precision)),
+	}, nil
+}
+
+// Add adds an element
+func (s *HyperLogLog) Add(x interface{}) errors.Error {
+	var (
+		p   = uint(s.Precision)
+		h   = hash64(x)
+		i   = h >> (64 - p)
+		rho = uint8(bits.LeadingZeros64(h<<p|1<<(p-1)) + 1)
+	)
+	if rho > s.Registers[i] {
+		s.Registers[i] = rho
+	}
+	return nil
+}
+
+// Merge merges other HyperLogLog with the same precision
+func (s *HyperLogLog) Merge(other Sketch) errors.Error {
+	o, ok := other.(*HyperLogLog)
+	if !ok {
+		return newMergeError("cannot merge %T into %T", other, s)
+	}
+	if o.Precision != s.Precision || len(o.Registers) != len(s.Registers) {
+		return newMergeError("cannot merge hyperloglog of precision %d into %d", o.Precision, s.Precision)
+	}
+	for i, r := range o.Registers {
+		if r > s.Registers[i] {
+			s.Registers[i] = r
+		}
+	}
+	return nil
+}
+
+// Empty returns an empty HyperLogLog with the same precision
+func (s *HyperLogLog) Empty() Sketch {
+	return &HyperLogLog{
+		Precision: s.Precision,
+		Registers: make([]uint8, len(s.Registers)),
+	}
+}
+
+// Estimate returns estimated number of distinct elements
+func (s *HyperLogLog) Estimate() uint64 {
+	var (
+		m     = float64(len(s.Registers))
+		sum   float64
+		zeros int
+	)
+	for _, r := range s.Registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	var alpha float64
+	switch len(s.Registers) {
+	case 16:
+		alpha = 0.673
+	case 32:
+		alpha = 0.697
+	case 64:
+		alpha = 0.709
+	default:
+		alpha = 0.7213 / (1 + 1.079/m)
+	}
+	e := alpha * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		// linear counting for small cardinalities
+		e = m * math.Log(m/float64(zeros))
+	}
+	return uint64(e + 0.5)
+}
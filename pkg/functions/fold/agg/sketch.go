@@ -0,0 +1,164 @@
+package agg
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"reflect"
+	"tools/pkg/errors"
+)
+
+type (
+	// Sketch is a mergeable approximate summary of elements.
+	// state is exported fields, sketches can be serialized by encoding/json and merged later
+	Sketch interface {
+		// Add adds an element
+		Add(x interface{}) errors.Error
+		// Merge merges other sketch of the same type and parameters into this
+		Merge(other Sketch) errors.Error
+		// Empty returns an empty sketch with the same parameters
+		Empty() Sketch
+		ownedBy(token *int) bool
+		own(token *int)
+	}
+
+	// ownership marks sketches created by an aggregator, they can be modified in place
+	ownership struct {
+		token *int
+	}
+)
+
+func (s *ownership) ownedBy(token *int) bool {
+	return s.token == token
+}
+
+func (s *ownership) own(token *int) {
+	s.token = token
+}
+
+func newMergeError(format string, a ...interface{}) errors.Error {
+	return errors.NewError().SetCode(errors.Fold).SetError(fmt.Errorf(format, a...))
+}
+
+// Merge adds elements into and merges sketches like proto.
+// elements are any values or sketches of the same type as proto, e.g. decoded results of other runs.
+// proto and sketches in the stream are not modified, nil elements are ignored.
+// Merge is associative and commutative, suitable for fold.TypeParallel
+//
+// aggregator :: a -> a -> a
+func Merge(proto Sketch) interface{} {
+	token := new(int)
+	var owned = func(s Sketch) (Sketch, error) {
+		if s.ownedBy(token) {
+			return s, nil
+		}
+		r := proto.Empty()
+		if err := r.Merge(s); err != nil {
+			return nil, err
+		}
+		r.own(token)
+		return r, nil
+	}
+	var add = func(s Sketch, x interface{}) (Sketch, error) {
+		if x == nil {
+			return s, nil
+		}
+		if o, ok := x.(Sketch); ok {
+			if err := s.Merge(o); err != nil {
+				return nil, err
+			}
+			return s, nil
+		}
+		if err := s.Add(x); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	return func(x, y interface{}) (interface{}, error) {
+		if x == nil && y == nil {
+			return nil, nil
+		}
+		sx, xok := x.(Sketch)
+		sy, yok := y.(Sketch)
+		switch {
+		case xok && sx.ownedBy(token):
+			return add(sx, y)
+		case yok:
+			o, err := owned(sy)
+			if err != nil {
+				return nil, err
+			}
+			return add(o, x)
+		case xok:
+			o, err := owned(sx)
+			if err != nil {
+				return nil, err
+			}
+			return add(o, y)
+		}
+		o, err := owned(proto.Empty())
+		if err != nil {
+			return nil, err
+		}
+		if o, err = add(o, x); err != nil {
+			return nil, err
+		}
+		return add(o, y)
+	}
+}
+
+// canonical converts numbers into float64, the same as decoded from JSON
+func canonical(x interface{}) interface{} {
+	switch v := x.(type) {
+	case float64, string, []byte, nil:
+		return x
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return x
+	}
+	v := reflect.ValueOf(x)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	}
+	return x
+}
+
+// hash64 hashes element by its canonical form, strings and []byte by their bytes,
+// numbers by their float64 values, so that they are the same after JSON round trip
+func hash64(x interface{}) uint64 {
+	h := fnv.New64a()
+	switch v := canonical(x).(type) {
+	case string:
+		_, _ = h.Write([]byte(v))
+	case []byte:
+		_, _ = h.Write(v)
+	case float64:
+		var b [9]byte
+		b[0] = 'n'
+		// adding 0 turns -0 into 0
+		binary.BigEndian.PutUint64(b[1:], math.Float64bits(v+0))
+		_, _ = h.Write(b[:])
+	default:
+		fmt.Fprintf(h, "%T:%v", v, v)
+	}
+	return mix64(h.Sum64())
+}
+
+// mix64 is finalizer of MurmurHash3, spreads entropy into every bit
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
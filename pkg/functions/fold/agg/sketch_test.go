@@ -0,0 +1,172 @@
+package agg_test
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"tools/pkg/functions"
+	"tools/pkg/functions/fold"
+	"tools/pkg/functions/fold/agg"
+	"tools/pkg/functions/iterator"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func foldSketch(t *testing.T, data interface{}, proto agg.Sketch, options ...fold.Option) agg.Sketch {
+	t.Helper()
+	var r []agg.Sketch
+	if err := functions.NewStream(iterator.MustNew(data)).Fold(agg.Merge(proto), options...).As(&r); err != nil {
+		t.Fatal(err)
+	}
+	return r[0]
+}
+
+func sequence(n, mod int) []int {
+	r := make([]int, n)
+	for i := range r {
+		r[i] = i % mod
+	}
+	return r
+}
+
+func TestHyperLogLog(t *testing.T) {
+	proto, err := agg.NewHyperLogLog(14)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		Comment string
+		Options []fold.Option
+	}{
+		{Comment: "foldr"},
+		{Comment: "parallel", Options: []fold.Option{fold.WithType(fold.TypeParallel), fold.WithChunkSize(1000)}},
+	} {
+		t.Run(tt.Comment, func(t *testing.T) {
+			h := foldSketch(t, sequence(50000, 20000), proto, tt.Options...).(*agg.HyperLogLog)
+			if e := float64(h.Estimate()); math.Abs(e-20000)/20000 > 0.03 {
+				t.Errorf("estimate %v", e)
+			}
+		})
+	}
+	if proto.Estimate() != 0 {
+		t.Error("proto should not be modified")
+	}
+	if _, err := agg.NewHyperLogLog(3); err == nil {
+		t.Error("should be error for invalid precision")
+	}
+}
+
+func TestHyperLogLogMergeSerialized(t *testing.T) {
+	proto, _ := agg.NewHyperLogLog(12)
+	var (
+		a = foldSketch(t, []string{"a", "b", "c"}, proto)
+		b = foldSketch(t, []string{"c", "d"}, proto)
+		h = []*agg.HyperLogLog{}
+	)
+	for _, x := range []agg.Sketch{a, b} {
+		buf, err := json.Marshal(x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var d agg.HyperLogLog
+		if err := json.Unmarshal(buf, &d); err != nil {
+			t.Fatal(err)
+		}
+		h = append(h, &d)
+	}
+	if e := foldSketch(t, h, proto).(*agg.HyperLogLog).Estimate(); e != 4 {
+		t.Errorf("estimate %d", e)
+	}
+
+	other, _ := agg.NewHyperLogLog(13)
+	err := functions.NewStream(iterator.MustNew([]agg.Sketch{a, other})).Fold(agg.Merge(proto)).Err()
+	if err == nil || !strings.Contains(err.Error(), "cannot merge hyperloglog of precision 13 into 12") {
+		t.Errorf("not expected error: %v", err)
+	}
+}
+
+func TestCountMinSketch(t *testing.T) {
+	proto, err := agg.NewCountMinSketch(1000, 5, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := append(sequence(3000, 1000), sequence(500, 3)...)
+	c := foldSketch(t, data, proto, fold.WithType(fold.TypeParallel), fold.WithChunkSize(100)).(*agg.CountMinSketch)
+	if c.N != 3500 {
+		t.Errorf("n %d", c.N)
+	}
+	if n := c.Count(1); n < 170 || n > 180 {
+		t.Errorf("count %d", n)
+	}
+	if len(c.Hitters) != 2 || c.Hitters[0].Count < 170 || c.Hitters[1].Count < 170 {
+		t.Errorf("hitters %v", c.Hitters)
+	}
+	for _, h := range c.Hitters {
+		if v := h.Value.(float64); v > 2 {
+			t.Errorf("not expected hitter %v", h)
+		}
+	}
+}
+
+func TestCountMinSketchMergeSerialized(t *testing.T) {
+	proto, _ := agg.NewCountMinSketch(100, 4, 2)
+	var (
+		a = foldSketch(t, []int{1, 1, 1, 2, 3}, proto)
+		b = foldSketch(t, []int{2, 2, 2, 3, 1}, proto)
+		c = []*agg.CountMinSketch{}
+	)
+	for _, x := range []agg.Sketch{a, b} {
+		buf, err := json.Marshal(x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var d agg.CountMinSketch
+		if err := json.Unmarshal(buf, &d); err != nil {
+			t.Fatal(err)
+		}
+		c = append(c, &d)
+	}
+	m := foldSketch(t, append(c, a.(*agg.CountMinSketch)), proto).(*agg.CountMinSketch)
+	if n := m.Count(1); n != 7 {
+		t.Errorf("count %d", n)
+	}
+	if n := m.Count(1.0); n != 7 {
+		t.Errorf("count of float %d", n)
+	}
+	// decoded sketch keeps tracking hitters of the same elements
+	c[1].Add(2)
+	expected := []agg.Hitter{{Value: 2.0, Count: 4}, {Value: 3.0, Count: 1}}
+	if actual := c[1].TopHitters(); !cmp.Equal(actual, expected) {
+		t.Errorf("  actual: %v\nexpected: %v", actual, expected)
+	}
+	expected = []agg.Hitter{{Value: 1.0, Count: 7}, {Value: 2.0, Count: 5}}
+	if actual := m.TopHitters(); !cmp.Equal(actual, expected) {
+		t.Errorf("  actual: %v\nexpected: %v", actual, expected)
+	}
+}
+
+func TestTDigest(t *testing.T) {
+	proto, err := agg.NewTDigest(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := foldSketch(t, sequence(100001, 100001), proto, fold.WithType(fold.TypeParallel), fold.WithChunkSize(999)).(*agg.TDigest)
+	n := len(d.Centroids)
+	for _, q := range []float64{0, 0.001, 0.01, 0.25, 0.5, 0.99, 1} {
+		if x := d.Quantile(q); math.Abs(x-q*100000) > 100000*0.005 {
+			t.Errorf("quantile %v: %v", q, x)
+		}
+	}
+	if len(d.Centroids) != n {
+		t.Errorf("quantile should not modify the digest: %d -> %d", n, len(d.Centroids))
+	}
+	d.Compress()
+	if len(d.Centroids) > 200 {
+		t.Errorf("too many centroids %d", len(d.Centroids))
+	}
+
+	if err := functions.NewStream(iterator.MustNew([]interface{}{1, "x"})).Fold(agg.Merge(proto)).Err(); err == nil || !strings.Contains(err.Error(), "not a number: x") {
+		t.Errorf("not expected error: %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package agg
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"tools/pkg/errors"
+)
+
+type (
+	// TDigest estimates quantiles of numbers, accurate at extreme quantiles.
+	// number of centroids is about Compression
+	TDigest struct {
+		ownership
+		Compression float64 `json:"compression"`
+		// Centroids may contain uncompressed ones, they are compressed on demand
+		Centroids []Centroid `json:"centroids"`
+		// Count is number of elements
+		Count float64 `json:"count"`
+		Min   float64 `json:"min"`
+		Max   float64 `json:"max"`
+	}
+
+	// Centroid is mean of neighboring elements
+	Centroid struct {
+		Mean  float64 `json:"mean"`
+		Count float64 `json:"count"`
+	}
+)
+
+// NewTDigest creates an empty TDigest, compression 100 is typical
+func NewTDigest(compression float64) (*TDigest, errors.Error) {
+	if !(compression >= 1) || math.IsInf(compression, 0) {
+		return nil, errors.NewError().SetCode(errors.Validate).SetError(
+			fmt.Errorf("compression must be at least 1: %v", compression))
+	}
+	return &TDigest{
+		Compression: compression,
+	}, nil
+}
+
+// Add adds a number, returns error with code errors.Conversion on non-number or NaN
+func (s *TDigest) Add(x interface{}) errors.Error {
+	f, err := toFloat(x)
+	if err != nil {
+		return err
+	}
+	return s.add(Centroid{Mean: f, Count: 1}, x)
+}
+
+func (s *TDigest) add(c Centroid, x interface{}) errors.Error {
+	if math.IsNaN(c.Mean) {
+		return errors.NewError().SetCode(errors.Conversion).SetError(fmt.Errorf("not a number: %v", x))
+	}
+	if s.Count == 0 || c.Mean < s.Min {
+		s.Min = c.Mean
+	}
+	if s.Count == 0 || c.Mean > s.Max {
+		s.Max = c.Mean
+	}
+	s.Count += c.Count
+	s.Centroids = append(s.Centroids, c)
+	if float64(len(s.Centroids)) > 5*s.Compression {
+		s.Compress()
+	}
+	return nil
+}
+
+// Merge merges other TDigest with the same compression
+func (s *TDigest) Merge(other Sketch) errors.Error {
+	o, ok := other.(*TDigest)
+	if !ok {
+		return newMergeError("cannot merge %T into %T", other, s)
+	}
+	if o.Compression != s.Compression {
+		return newMergeError("cannot merge t-digest of compression %v into %v", o.Compression, s.Compression)
+	}
+	if o.Count == 0 {
+		return nil
+	}
+	var (
+		count    = s.Count
+		min, max = s.Min, s.Max
+	)
+	for _, c := range o.Centroids {
+		if err := s.add(c, c.Mean); err != nil {
+			return err
+		}
+	}
+	if count > 0 {
+		s.Min, s.Max = math.Min(min, o.Min), math.Max(max, o.Max)
+	} else {
+		s.Min, s.Max = o.Min, o.Max
+	}
+	return nil
+}
+
+// Empty returns an empty TDigest with the same compression
+func (s *TDigest) Empty() Sketch {
+	return &TDigest{
+		Compression: s.Compression,
+	}
+}
+
+// Compress merges neighboring centroids as long as they fit in the scale function k1
+func (s *TDigest) Compress() {
+	s.Centroids = s.compressed()
+}
+
+// compressed returns compressed centroids without modifying the digest
+func (s *TDigest) compressed() []Centroid {
+	cs := append([]Centroid{}, s.Centroids...)
+	if len(cs) < 2 {
+		return cs
+	}
+	sort.SliceStable(cs, func(i, j int) bool { return cs[i].Mean < cs[j].Mean })
+	var (
+		d     = s.Compression
+		k     = func(q float64) float64 { return d / (2 * math.Pi) * math.Asin(2*q-1) }
+		limit = func(q float64) float64 {
+			x := k(q) + 1
+			if x >= d/4 {
+				return 1
+			}
+			return (math.Sin(x*2*math.Pi/d) + 1) / 2
+		}
+		merged = []Centroid{}
+		cur    = cs[0]
+		before float64
+		qLimit = limit(0)
+	)
+	for _, c := range cs[1:] {
+		if (before+cur.Count+c.Count)/s.Count <= qLimit {
+			cur.Count += c.Count
+			cur.Mean += (c.Mean - cur.Mean) * c.Count / cur.Count
+			continue
+		}
+		merged = append(merged, cur)
+		before += cur.Count
+		qLimit = limit(before / s.Count)
+		cur = c
+	}
+	return append(merged, cur)
+}
+
+// Quantile returns estimated q-quantile, NaN if no elements.
+// the digest is not modified, safe for concurrent readers
+func (s *TDigest) Quantile(q float64) float64 {
+	if s.Count == 0 || math.IsNaN(q) {
+		return math.NaN()
+	}
+	var (
+		cs     = s.compressed()
+		target = q * s.Count
+		lerp   = func(x0, y0, x1, y1, x float64) float64 {
+			if x1 <= x0 {
+				return y0
+			}
+			return y0 + (y1-y0)*(x-x0)/(x1-x0)
+		}
+	)
+	if q <= 0 {
+		return s.Min
+	}
+	if q >= 1 {
+		return s.Max
+	}
+	center := cs[0].Count / 2
+	if target < center {
+		return lerp(0, s.Min, center, cs[0].Mean, target)
+	}
+	for i := 0; i+1 < len(cs); i++ {
+		next := center + (cs[i].Count+cs[i+1].Count)/2
+		if target < next {
+			return lerp(center, cs[i].Mean, next, cs[i+1].Mean, target)
+		}
+		center = next
+	}
+	return lerp(center, cs[len(cs)-1].Mean, s.Count, s.Max, target)
+}
@@ -12,9 +12,10 @@ var (
 )
 
 type (
-	// Aggregator :: a -> b -> b or b -> a -> b
+	// Aggregator :: a -> b -> b or b -> a -> b.
+	// aggregator may return error as the second result, e.g. b -> a -> (b, error)
 	Aggregator interface {
-		Apply(x, acc interface{}) (interface{}, error)
+		Apply(x, acc interface{}, options ...reflection.Option) (interface{}, error)
 		Type() AggregatorType
 		// IV returns initial zero value
 		IV() interface{}
@@ -41,20 +42,24 @@ const (
 	PerfectAggregator
 )
 
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// isBinary returns true if t is func(x, y) z or func(x, y) (z, error)
+func isBinary(t reflect.Type) bool {
+	return t.Kind() == reflect.Func && t.NumIn() == 2 &&
+		(t.NumOut() == 1 || t.NumOut() == 2 && t.Out(1) == errorType)
+}
+
 // IsRightAggregator returns true if f :: a -> b -> b
 func IsRightAggregator(f interface{}) bool {
 	t := reflect.TypeOf(f)
-	return t.Kind() == reflect.Func &&
-		t.NumIn() == 2 && t.NumOut() == 1 &&
-		t.In(1).String() == t.Out(0).String()
+	return isBinary(t) && t.In(1).String() == t.Out(0).String()
 }
 
 // IsLeftAggregator returns true if f :: b -> a -> b
 func IsLeftAggregator(f interface{}) bool {
 	t := reflect.TypeOf(f)
-	return t.Kind() == reflect.Func &&
-		t.NumIn() == 2 && t.NumOut() == 1 &&
-		t.In(0).String() == t.Out(0).String()
+	return isBinary(t) && t.In(0).String() == t.Out(0).String()
 }
 
 // IsPerfectAggregator returns true if f :: a -> a -> a
@@ -115,20 +120,34 @@ func (s *aggregator) Type() AggregatorType {
 	return s.at
 }
 
-func (s *aggregator) Apply(x, y interface{}) (interface{}, error) {
+// Apply returns error of the aggregator as is
+func (s *aggregator) Apply(x, y interface{}, options ...reflection.Option) (interface{}, error) {
 	var (
 		vx, vy reflect.Value
 	)
 	if err := func() error {
 		var err error
-		if vx, err = reflection.ConvertShallow(x, s.t.In(0)); err != nil {
+		if vx, err = reflection.ConvertShallow(x, s.t.In(0), options...); err != nil {
 			return err
 		}
-		vy, err = reflection.ConvertShallow(y, s.t.In(1))
+		vy, err = reflection.ConvertShallow(y, s.t.In(1), options...)
 		return err
 	}(); err != nil {
-		return nil, errors.NewError().SetCode(errors.Conversion).SetError(fmt.Errorf("invalid argument for aggregate: %v", err))
+		return nil, errors.NewError().SetCode(errors.Conversion).SetError(fmt.Errorf("invalid argument for aggregate: %w", err))
 	}
 	r := s.v.Call([]reflect.Value{vx, vy})
+	if len(r) == 2 && !r[1].IsNil() {
+		return nil, r[1].Interface().(error)
+	}
 	return r[0].Interface(), nil
 }
+
+// converting applies conversion options to arguments of the aggregator
+type converting struct {
+	Aggregator
+	options []reflection.Option
+}
+
+func (s *converting) Apply(x, y interface{}, options ...reflection.Option) (interface{}, error) {
+	return s.Aggregator.Apply(x, y, append(append([]reflection.Option{}, s.options...), options...)...)
+}
@@ -2,9 +2,12 @@ package fold
 
 import (
 	"fmt"
+	"runtime"
+	"tools/pkg/conv/reflection"
 	"tools/pkg/errors"
 	"tools/pkg/functions/executor"
 	"tools/pkg/functions/iterator"
+	"tools/pkg/log"
 )
 
 var (
@@ -21,6 +24,12 @@ type (
 		iter  iterator.Iterator
 		ft    Type
 		iv    interface{}
+
+		conversion []reflection.Option
+		// for TypeParallel
+		chunkSize     int
+		workers       int
+		deterministic bool
 	}
 
 	// Option changes option of Executor
@@ -40,14 +49,17 @@ const (
 	TypeT
 	// TypeI for Foldi
 	TypeI
+	// TypeParallel for fold on goroutines
+	TypeParallel
 )
 
 var (
 	validExecutorMap = map[Type]func(AggregatorType) bool{
-		TypeR: func(at AggregatorType) bool { return at == RightAggregator || at == PerfectAggregator },
-		TypeL: func(at AggregatorType) bool { return at == LeftAggregator || at == PerfectAggregator },
-		TypeT: func(at AggregatorType) bool { return at == PerfectAggregator },
-		TypeI: func(at AggregatorType) bool { return at == PerfectAggregator },
+		TypeR:        func(at AggregatorType) bool { return at == RightAggregator || at == PerfectAggregator },
+		TypeL:        func(at AggregatorType) bool { return at == LeftAggregator || at == PerfectAggregator },
+		TypeT:        func(at AggregatorType) bool { return at == PerfectAggregator },
+		TypeI:        func(at AggregatorType) bool { return at == PerfectAggregator },
+		TypeParallel: func(at AggregatorType) bool { return at == PerfectAggregator },
 	}
 	funcMap = map[Type]Func{
 		TypeR: Foldr,
@@ -83,14 +95,38 @@ func WithHook(ht executor.HookType, h interface{}) Option {
 	}
 }
 
-// NewExector creates Executor with default fold type R and initial zero value
+// WithLogger logs progress at debug level with field stage=fold
+func WithLogger(l log.Logger) Option {
+	return func(s *Executor) {
+		s.iter = executor.AddLogHooks(s.hooks, l.With("stage", "fold"), s.iter)
+	}
+}
+
+// WithConversion specifies options of conversion of arguments of aggregator and hooks,
+// e.g. reflection.WithStrict rejects lossy accumulators
+func WithConversion(options ...reflection.Option) Option {
+	return func(s *Executor) {
+		s.conversion = append(s.conversion, options...)
+		s.hooks.SetConversion(s.conversion...)
+	}
+}
+
+// NewExector creates Executor with initial zero value and default fold type R,
+// L for left aggregators
 func NewExecutor(f Aggregator, iter iterator.Iterator, options ...Option) (*Executor, errors.Error) {
+	ft := TypeR
+	if f.Type() == LeftAggregator {
+		ft = TypeL
+	}
 	executor := &Executor{
 		hooks: executor.NewHookable(),
 		agg:   f,
 		iter:  iter,
-		ft:    TypeR,
+		ft:    ft,
 		iv:    f.IV(),
+
+		chunkSize: defaultChunkSize,
+		workers:   runtime.NumCPU(),
 	}
 	for _, opt := range options {
 		opt(executor)
@@ -98,14 +134,28 @@ func NewExecutor(f Aggregator, iter iterator.Iterator, options ...Option) (*Exec
 	if !isValidExecutor(executor.ft, f.Type()) {
 		return nil, InvalidType
 	}
+	if executor.chunkSize < 1 {
+		return nil, InvalidChunkSize
+	}
+	if executor.workers < 1 {
+		return nil, InvalidWorkers
+	}
 	return executor, nil
 }
 
 func (s *Executor) Execute() (interface{}, error) {
-	if f, ok := funcMap[s.ft]; ok {
+	f, ok := funcMap[s.ft]
+	if s.ft == TypeParallel {
+		f, ok = s.foldParallel, true
+	}
+	if ok {
+		var agg Aggregator = s.agg
+		if len(s.conversion) > 0 {
+			agg = &converting{Aggregator: s.agg, options: s.conversion}
+		}
 		s.hooks.Execute(executor.BeforeHook, s.iter)
 		s.hooks.Execute(executor.RunningHook, s.iv, s.iter)
-		ret, err := f(s.agg, s.iv, s.iter)
+		ret, err := f(agg, s.iv, s.iter)
 		if err == nil {
 			s.hooks.Execute(executor.RunningResultHook, ret)
 			s.hooks.Execute(executor.AfterHook)
@@ -118,7 +168,7 @@ func (s *Executor) Execute() (interface{}, error) {
 // Foldr requires aggregator :: a -> b -> b
 func Foldr(f Aggregator, acc interface{}, iter iterator.Iterator) (interface{}, error) {
 	x, err := iter.Next()
-	if err == iterator.EOI {
+	if errors.Is(err, iterator.EOI) {
 		return acc, nil
 	}
 	if err != nil {
@@ -131,56 +181,49 @@ func Foldr(f Aggregator, acc interface{}, iter iterator.Iterator) (interface{},
 	return f.Apply(x, ret)
 }
 
-// Foldl requires aggregator :: b -> a -> b
+// Foldl requires aggregator :: b -> a -> b.
+// runs in constant stack unlike Foldr
 func Foldl(f Aggregator, acc interface{}, iter iterator.Iterator) (interface{}, error) {
-	x, err := iter.Next()
-	if err == iterator.EOI {
-		return acc, nil
-	}
-	if err != nil {
-		return nil, err
-	}
-	ret, err := f.Apply(acc, x)
-	if err != nil {
-		return nil, err
+	for {
+		x, err := iter.Next()
+		if errors.Is(err, iterator.EOI) {
+			return acc, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if acc, err = f.Apply(acc, x); err != nil {
+			return nil, err
+		}
 	}
-	return Foldl(f, ret, iter)
 }
 
 // Foldt requires aggregator :: a -> a -> a
 func Foldt(f Aggregator, acc interface{}, iter iterator.Iterator) (interface{}, error) {
 	x, err := iter.Next()
-	if err == iterator.EOI {
+	if errors.Is(err, iterator.EOI) {
 		return acc, nil
 	}
 	if err != nil {
 		return nil, err
 	}
 	y, err := iter.Next()
-	if err == iterator.EOI {
+	if errors.Is(err, iterator.EOI) {
 		return x, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	// unyield one
-	var (
-		isYieldedOne bool
-		piter        = pairs(f, iterator.MustNew(iterator.Func(func() (interface{}, error) {
-			if !isYieldedOne {
-				isYieldedOne = true
-				return y, nil
-			}
-			return iter.Next()
-		})))
-	)
-	return Foldt(f, acc, piter)
+	p := iterator.NewPeekable(iter)
+	p.Unread(y)
+	p.Unread(x)
+	return Foldt(f, acc, pairs(f, p))
 }
 
 // Foldi requires aggregator :: a -> a -> a
 func Foldi(f Aggregator, acc interface{}, iter iterator.Iterator) (interface{}, error) {
 	x, err := iter.Next()
-	if err == iterator.EOI {
+	if errors.Is(err, iterator.EOI) {
 		return acc, nil
 	}
 	if err != nil {
@@ -204,7 +247,7 @@ func pairs(f Aggregator, iter iterator.Iterator) iterator.Iterator {
 			return nil, err
 		}
 		y, err := iter.Next()
-		if err == iterator.EOI {
+		if errors.Is(err, iterator.EOI) {
 			isEOI = true
 			return x, nil
 		}
@@ -13,11 +13,12 @@ func _() {
 	_ = x[TypeL-2]
 	_ = x[TypeT-3]
 	_ = x[TypeI-4]
+	_ = x[TypeParallel-5]
 }
 
-const _Type_name = "TypeUnknownTypeRTypeLTypeTTypeI"
+const _Type_name = "TypeUnknownTypeRTypeLTypeTTypeITypeParallel"
 
-var _Type_index = [...]uint8{0, 11, 16, 21, 26, 31}
+var _Type_index = [...]uint8{0, 11, 16, 21, 26, 31, 43}
 
 func (i Type) String() string {
 	if i < 0 || i >= Type(len(_Type_index)-1) {
@@ -0,0 +1,212 @@
+package fold
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"tools/pkg/errors"
+	"tools/pkg/functions/iterator"
+)
+
+var (
+	InvalidChunkSize = errors.NewError().SetCode(errors.Fold).SetError(fmt.Errorf("chunk size must be positive"))
+	InvalidWorkers   = errors.NewError().SetCode(errors.Fold).SetError(fmt.Errorf("workers must be positive"))
+)
+
+const (
+	defaultChunkSize = 1024
+)
+
+// WithChunkSize specifies number of elements folded by a worker at once for TypeParallel.
+// default: 1024
+func WithChunkSize(n int) Option {
+	return func(s *Executor) {
+		s.chunkSize = n
+	}
+}
+
+// WithWorkers specifies number of goroutines for TypeParallel.
+// default: runtime.NumCPU()
+func WithWorkers(n int) Option {
+	return func(s *Executor) {
+		s.workers = n
+	}
+}
+
+// WithDeterministic combines partial results in the order of the stream for TypeParallel.
+// required if aggregator is not commutative
+func WithDeterministic() Option {
+	return func(s *Executor) {
+		s.deterministic = true
+	}
+}
+
+type (
+	chunk struct {
+		index int
+		elems []interface{}
+	}
+
+	partial struct {
+		index int
+		v     interface{}
+	}
+
+	parallelFolder struct {
+		f             Aggregator
+		workers       int
+		deterministic bool
+		mux           sync.Mutex
+		err           error
+		done          chan struct{}
+	}
+)
+
+// foldParallel requires aggregator :: a -> a -> a, associative.
+// folds chunks of the stream on workers and combines partial results in a tree.
+// returns acc if the stream is empty
+func (s *Executor) foldParallel(f Aggregator, acc interface{}, iter iterator.Iterator) (interface{}, error) {
+	p := &parallelFolder{
+		f:             f,
+		workers:       s.workers,
+		deterministic: s.deterministic,
+		done:          make(chan struct{}),
+	}
+	var (
+		chunks   = make(chan *chunk, s.workers)
+		results  = make(chan *partial, s.workers)
+		partials = []*partial{}
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < s.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range chunks {
+				if v, ok := p.foldChunk(c.elems); ok {
+					results <- &partial{index: c.index, v: v}
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	go func() {
+		defer close(chunks)
+		for i := 0; ; i++ {
+			elems := make([]interface{}, 0, s.chunkSize)
+			for len(elems) < s.chunkSize {
+				x, err := iter.Next()
+				if errors.Is(err, iterator.EOI) {
+					break
+				}
+				if err != nil {
+					p.fail(err)
+					return
+				}
+				elems = append(elems, x)
+			}
+			if len(elems) == 0 {
+				return
+			}
+			select {
+			case chunks <- &chunk{index: i, elems: elems}:
+			case <-p.done:
+				return
+			}
+			if len(elems) < s.chunkSize {
+				return
+			}
+		}
+	}()
+	for r := range results {
+		partials = append(partials, r)
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	if len(partials) == 0 {
+		return acc, nil
+	}
+	if p.deterministic {
+		sort.Slice(partials, func(i, j int) bool { return partials[i].index < partials[j].index })
+	}
+	vs := make([]interface{}, len(partials))
+	for i, x := range partials {
+		vs[i] = x.v
+	}
+	return p.combine(vs)
+}
+
+func (s *parallelFolder) fail(err error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.err == nil {
+		s.err = err
+		close(s.done)
+	}
+}
+
+func (s *parallelFolder) failed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// foldChunk folds elements from left to right
+func (s *parallelFolder) foldChunk(elems []interface{}) (interface{}, bool) {
+	acc := elems[0]
+	for _, x := range elems[1:] {
+		if s.failed() {
+			return nil, false
+		}
+		ret, err := s.f.Apply(acc, x)
+		if err != nil {
+			s.fail(err)
+			return nil, false
+		}
+		acc = ret
+	}
+	return acc, true
+}
+
+// combine combines adjacent pairs level by level on workers
+func (s *parallelFolder) combine(vs []interface{}) (interface{}, error) {
+	for len(vs) > 1 {
+		var (
+			next = make([]interface{}, (len(vs)+1)/2)
+			sem  = make(chan struct{}, s.workers)
+			wg   sync.WaitGroup
+		)
+		for i := 0; i+1 < len(vs); i += 2 {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				ret, err := s.f.Apply(vs[i], vs[i+1])
+				if err != nil {
+					s.fail(err)
+					return
+				}
+				next[i/2] = ret
+			}(i)
+		}
+		if len(vs)%2 == 1 {
+			next[len(next)-1] = vs[len(vs)-1]
+		}
+		wg.Wait()
+		if s.err != nil {
+			return nil, s.err
+		}
+		vs = next
+	}
+	return vs[0], nil
+}
@@ -15,11 +15,15 @@ func _() {
 	_ = x[SortScriptType-4]
 	_ = x[FlatScriptType-5]
 	_ = x[LiftScriptType-6]
+	_ = x[FlatMapScriptType-7]
+	_ = x[SampleReservoirScriptType-8]
+	_ = x[SampleFractionScriptType-9]
+	_ = x[SampleStratifiedScriptType-10]
 }
 
-const _ScriptType_name = "UnknownScriptTypeMapScriptTypeFilterScriptTypeFoldScriptTypeSortScriptTypeFlatScriptTypeLiftScriptType"
+const _ScriptType_name = "UnknownScriptTypeMapScriptTypeFilterScriptTypeFoldScriptTypeSortScriptTypeFlatScriptTypeLiftScriptTypeFlatMapScriptTypeSampleReservoirScriptTypeSampleFractionScriptTypeSampleStratifiedScriptType"
 
-var _ScriptType_index = [...]uint8{0, 17, 30, 46, 60, 74, 88, 102}
+var _ScriptType_index = [...]uint8{0, 17, 30, 46, 60, 74, 88, 102, 119, 144, 168, 194}
 
 func (i ScriptType) String() string {
 	if i < 0 || i >= ScriptType(len(_ScriptType_index)-1) {
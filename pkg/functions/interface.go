@@ -6,6 +6,7 @@ package functions
 import (
 	"fmt"
 	"reflect"
+	"time"
 	"tools/pkg/conv/reflection"
 	"tools/pkg/errors"
 	"tools/pkg/functions/consume"
@@ -15,7 +16,11 @@ import (
 	"tools/pkg/functions/iterator"
 	"tools/pkg/functions/lift"
 	"tools/pkg/functions/mapper"
+	"tools/pkg/functions/peek"
+	"tools/pkg/functions/sample"
 	"tools/pkg/functions/sorter"
+	"tools/pkg/functions/throttle"
+	"tools/pkg/log"
 )
 
 type (
@@ -36,26 +41,81 @@ type (
 		//
 		// consumer :: a
 		Consume(consumer interface{}, options ...consume.Option) error
-		// As assign stream into reference v
-		As(v interface{}) error
+		// As assign stream into reference v.
+		// options are appended to conversion options of the stream
+		As(v interface{}, options ...reflection.Option) error
 		// Sort sort stream
 		//
 		// less :: a -> a -> bool
 		Sort(less interface{}, options ...sorter.Option) Stream
+		// SortBy sort stream stably by keys, former keys take precedence.
+		// key is a key function or sorter.Asc, sorter.Desc
+		//
+		// key :: a -> k
+		SortBy(keys ...interface{}) Stream
+		// TopK yield k greatest elements in descending order.
+		// memory is O(k), equal elements keep the order they arrived
+		//
+		// less :: a -> a -> bool
+		TopK(k int, less interface{}, options ...sorter.Option) Stream
+		// BottomK yield k least elements in ascending order.
+		// memory is O(k), equal elements keep the order they arrived
+		//
+		// less :: a -> a -> bool
+		BottomK(k int, less interface{}, options ...sorter.Option) Stream
 		// Flat flatten stream, single level
 		Flat(options ...flat.Option) Stream
+		// FlatMap convert each elements into iterables and flatten them
+		//
+		// mapper :: a -> [b]
+		FlatMap(mapper interface{}, options ...flat.Option) Stream
 		// Lift lift up stream, single level, into []interface{}
 		Lift(options ...lift.Option) Stream
+		// Throttle limit rate of pulling elements by token bucket.
+		// ratePerSecond is number of elements refilled per second, burst is capacity of the bucket
+		Throttle(ratePerSecond float64, burst int, options ...throttle.Option) Stream
+		// Sample yield the latest element per interval
+		Sample(interval time.Duration, options ...sample.Option) Stream
+		// SampleReservoir yield k elements sampled uniformly, in the order they arrived.
+		// see sample.WithSeed
+		SampleReservoir(k int, options ...sample.Option) Stream
+		// SampleFraction yield each element with probability p.
+		// see sample.WithSeed
+		SampleFraction(p float64, options ...sample.Option) Stream
+		// SampleStratified yield elements sampled uniformly per key, in the order they arrived.
+		// see sample.WithStratumSize and sample.WithSeed
+		//
+		// key :: a -> k
+		SampleStratified(key interface{}, options ...sample.Option) Stream
+		// Peek invoke f with each element, yield elements as is
+		//
+		// f :: a
+		Peek(f interface{}, options ...peek.Option) Stream
+		// Debug log each element with its index and type into logger at debug level, yield elements as is.
+		// see peek.WithEvery and peek.WithMaxLength to sample and truncate logs
+		Debug(logger log.Logger, label string, options ...peek.Option) Stream
 		// Err get error during streaming.
 		// should invoke before extracting result.
-		// stream is nil stream when err is not nil
+		// stream is nil stream when err is not nil.
+		// with ErrorSkip, returns *errors.MultiError of skipped elements after extracting result
 		Err() error
 	}
 
 	stream struct {
-		iter iterator.Iterator
-		err  error
+		iter       iterator.Iterator
+		err        error
+		conversion []reflection.Option
+		policy     ErrorPolicy
+		examples   int
+		// errs collects skipped errors, shared with derived streams
+		errs   *errors.MultiError
+		index  int
+		logger log.Logger
 	}
+
+	// StreamOption changes option of Stream.
+	// options are inherited by streams derived from the stream
+	StreamOption func(*stream)
 )
 
 const (
@@ -66,11 +126,86 @@ const (
 )
 
 func newStreamError(code errors.Code, msg string, err error) error {
-	return errors.NewError().SetCode(code).SetError(fmt.Errorf("%s: %v", msg, err))
+	return errors.Wrap(code, err, msg)
+}
+
+// WithConversion specifies options of conversion of arguments of functions and hooks, and Stream.As
+func WithConversion(options ...reflection.Option) StreamOption {
+	return func(s *stream) {
+		s.conversion = append(s.conversion, options...)
+	}
+}
+
+//go:generate stringer -type=ErrorPolicy -output generated.errorpolicy_string.go
+type ErrorPolicy int
+
+const (
+	UnknownErrorPolicy ErrorPolicy = iota
+	// ErrorStop stops the stream at the first error
+	ErrorStop
+	// ErrorSkip skips failed elements and collects errors into *errors.MultiError.
+	// io and system errors still stop the stream
+	ErrorSkip
+)
+
+// WithErrorPolicy specifies how to handle errors of elements.
+// default: ErrorStop
+func WithErrorPolicy(p ErrorPolicy) StreamOption {
+	return func(s *stream) {
+		s.policy = p
+	}
+}
+
+// WithErrorExamples keeps n examples per error code for ErrorSkip.
+// default: 5
+func WithErrorExamples(n int) StreamOption {
+	return func(s *stream) {
+		s.examples = n
+	}
+}
+
+// WithLogger injects logger into executors of the stream and derived streams,
+// skipped errors are logged at warn level
+func WithLogger(l log.Logger) StreamOption {
+	return func(s *stream) {
+		s.logger = l
+	}
+}
+
+// WithStrictConversion converts numbers without loss, see reflection.WithStrict.
+// applies to arguments of functions and hooks of operators as well as Stream.As
+func WithStrictConversion() StreamOption {
+	return WithConversion(reflection.WithStrict())
 }
 
-func NewStream(iter iterator.Iterator) Stream {
-	return &stream{iter: iter}
+func NewStream(iter iterator.Iterator, options ...StreamOption) Stream {
+	s := &stream{
+		iter:   iter,
+		policy: ErrorStop,
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+	switch s.policy {
+	case ErrorStop:
+	case ErrorSkip:
+		s.errs = errors.NewMultiError(s.examples)
+	default:
+		return NewNilStream(errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("invalid error policy: %v", s.policy)))
+	}
+	return s
+}
+
+// newStream creates a stream derived from the stream by operator label
+func (s *stream) newStream(iter iterator.Iterator, label string) Stream {
+	return &stream{
+		iter:       tap(iter, label),
+		conversion: s.conversion,
+		policy:     s.policy,
+		examples:   s.examples,
+		errs:       s.errs,
+		logger:     s.logger,
+	}
 }
 
 // NewNilStream create stream that yield no items.
@@ -83,14 +218,51 @@ func NewNilStream(err error) Stream {
 }
 
 func (s *stream) Next() (interface{}, error) {
-	return s.iter.Next()
+	var last error
+	for {
+		x, err := s.iter.Next()
+		if err == nil {
+			s.index++
+			return x, nil
+		}
+		if s.errs == nil || errors.Is(err, iterator.EOI) || isSameError(err, last) {
+			return x, err
+		}
+		switch errors.CodeOf(err) {
+		case errors.IO, errors.System:
+			return x, err
+		}
+		s.index++
+		s.errs.Add(err, s.index)
+		if s.logger != nil {
+			s.logger.With("index", s.index).Warn("skipped: %v", err)
+		}
+		last = err
+	}
+}
+
+// isSameError returns true if iterator returns the same error again, it won't recover
+func isSameError(err, last error) bool {
+	return last != nil && reflect.TypeOf(err) == reflect.TypeOf(last) && reflect.TypeOf(err).Comparable() && err == last
 }
 
 func (s *stream) Err() error {
-	return s.err
+	if s.err != nil {
+		return s.err
+	}
+	if s.errs != nil && s.errs.Len() > 0 {
+		return s.errs
+	}
+	return nil
 }
 
 func (s *stream) Map(mapperFunc interface{}, options ...mapper.Option) Stream {
+	if s.logger != nil {
+		options = append(append([]mapper.Option{}, options...), mapper.WithLogger(s.logger))
+	}
+	if len(s.conversion) > 0 {
+		options = append(append([]mapper.Option{}, options...), mapper.WithConversion(s.conversion...))
+	}
 	f, err := mapper.NewMapper(mapperFunc)
 	if err != nil {
 		return NewNilStream(newStreamError(errors.Map, errMsgInvalidFunction, err))
@@ -99,10 +271,16 @@ func (s *stream) Map(mapperFunc interface{}, options ...mapper.Option) Stream {
 	if err != nil {
 		return NewNilStream(newStreamError(errors.Map, errMsgCannotCreateExecutor, err))
 	}
-	return NewStream(mapExecutor.Execute())
+	return s.newStream(mapExecutor.Execute(), "map")
 }
 
 func (s *stream) Filter(predicateFunc interface{}, options ...filter.Option) Stream {
+	if s.logger != nil {
+		options = append(append([]filter.Option{}, options...), filter.WithLogger(s.logger))
+	}
+	if len(s.conversion) > 0 {
+		options = append(append([]filter.Option{}, options...), filter.WithConversion(s.conversion...))
+	}
 	f, err := filter.NewPredicate(predicateFunc)
 	if err != nil {
 		return NewNilStream(newStreamError(errors.Filter, errMsgInvalidFunction, err))
@@ -111,10 +289,16 @@ func (s *stream) Filter(predicateFunc interface{}, options ...filter.Option) Str
 	if err != nil {
 		return NewNilStream(newStreamError(errors.Filter, errMsgCannotCreateExecutor, err))
 	}
-	return NewStream(filterExecutor.Execute())
+	return s.newStream(filterExecutor.Execute(), "filter")
 }
 
 func (s *stream) Fold(aggregator interface{}, options ...fold.Option) Stream {
+	if s.logger != nil {
+		options = append(append([]fold.Option{}, options...), fold.WithLogger(s.logger))
+	}
+	if len(s.conversion) > 0 {
+		options = append(append([]fold.Option{}, options...), fold.WithConversion(s.conversion...))
+	}
 	var err error
 	f, err := fold.NewAggregator(aggregator)
 	if err != nil {
@@ -128,10 +312,16 @@ func (s *stream) Fold(aggregator interface{}, options ...fold.Option) Stream {
 	if err != nil {
 		return NewNilStream(newStreamError(errors.Fold, errMsgCannotExecute, err))
 	}
-	return NewStream(iterator.MustNewFromInterfaces(ret))
+	return s.newStream(iterator.MustNewFromInterfaces(ret), "fold")
 }
 
 func (s *stream) Consume(consumer interface{}, options ...consume.Option) error {
+	if s.logger != nil {
+		options = append(append([]consume.Option{}, options...), consume.WithLogger(s.logger))
+	}
+	if len(s.conversion) > 0 {
+		options = append(append([]consume.Option{}, options...), consume.WithConversion(s.conversion...))
+	}
 	f, err := consume.NewConsumer(consumer)
 	if err != nil {
 		return newStreamError(errors.Consume, errMsgInvalidFunction, err)
@@ -143,12 +333,13 @@ func (s *stream) Consume(consumer interface{}, options ...consume.Option) error
 	return consumeExecutor.Execute()
 }
 
-func (s *stream) As(v interface{}) error {
+func (s *stream) As(v interface{}, options ...reflection.Option) error {
 	slice, err := iterator.ToSlice(s)
 	if err != nil {
 		return err
 	}
-	sv, err := reflection.Convert(slice, reflect.TypeOf(v).Elem())
+	conversion := append(append([]reflection.Option{}, s.conversion...), options...)
+	sv, err := reflection.Convert(slice, reflect.TypeOf(v).Elem(), conversion...)
 	if err != nil {
 		return err
 	}
@@ -157,6 +348,12 @@ func (s *stream) As(v interface{}) error {
 }
 
 func (s *stream) Sort(less interface{}, options ...sorter.Option) Stream {
+	if s.logger != nil {
+		options = append(append([]sorter.Option{}, options...), sorter.WithLogger(s.logger))
+	}
+	if len(s.conversion) > 0 {
+		options = append(append([]sorter.Option{}, options...), sorter.WithConversion(s.conversion...))
+	}
 	var err error
 	f, err := sorter.NewSorter(less)
 	if err != nil {
@@ -170,18 +367,93 @@ func (s *stream) Sort(less interface{}, options ...sorter.Option) Stream {
 	if err != nil {
 		return NewNilStream(newStreamError(errors.Sort, errMsgCannotCompare, err))
 	}
-	return NewStream(iter)
+	return s.newStream(iter, "sort")
+}
+
+func (s *stream) SortBy(keys ...interface{}) Stream {
+	var err error
+	ks, err := sorter.NewKeys(keys...)
+	if err != nil {
+		return NewNilStream(newStreamError(errors.Sort, errMsgInvalidFunction, err))
+	}
+	options := []sorter.Option{}
+	if s.logger != nil {
+		options = append(options, sorter.WithLogger(s.logger))
+	}
+	if len(s.conversion) > 0 {
+		options = append(options, sorter.WithConversion(s.conversion...))
+	}
+	sortExecutor, err := sorter.NewKeyExecutor(ks, s, options...)
+	if err != nil {
+		return NewNilStream(newStreamError(errors.Sort, errMsgCannotCreateExecutor, err))
+	}
+	iter, err := sortExecutor.Execute()
+	if err != nil {
+		return NewNilStream(newStreamError(errors.Sort, errMsgCannotCompare, err))
+	}
+	return s.newStream(iter, "sortby")
+}
+
+func (s *stream) TopK(k int, less interface{}, options ...sorter.Option) Stream {
+	return s.selectK("topk", sorter.NewTopKExecutor, k, less, options)
+}
+
+func (s *stream) BottomK(k int, less interface{}, options ...sorter.Option) Stream {
+	return s.selectK("bottomk", sorter.NewBottomKExecutor, k, less, options)
+}
+
+func (s *stream) selectK(label string, newExecutor func(sorter.Sorter, int, iterator.Iterator, ...sorter.Option) (*sorter.Executor, errors.Error), k int, less interface{}, options []sorter.Option) Stream {
+	if s.logger != nil {
+		options = append(append([]sorter.Option{}, options...), sorter.WithLogger(s.logger))
+	}
+	if len(s.conversion) > 0 {
+		options = append(append([]sorter.Option{}, options...), sorter.WithConversion(s.conversion...))
+	}
+	var err error
+	f, err := sorter.NewSorter(less)
+	if err != nil {
+		return NewNilStream(newStreamError(errors.Sort, errMsgInvalidFunction, err))
+	}
+	sortExecutor, err := newExecutor(f, k, s, options...)
+	if err != nil {
+		return NewNilStream(newStreamError(errors.Sort, errMsgCannotCreateExecutor, err))
+	}
+	iter, err := sortExecutor.Execute()
+	if err != nil {
+		return NewNilStream(newStreamError(errors.Sort, errMsgCannotCompare, err))
+	}
+	return s.newStream(iter, label)
 }
 
 func (s *stream) Flat(options ...flat.Option) Stream {
+	if s.logger != nil {
+		options = append(append([]flat.Option{}, options...), flat.WithLogger(s.logger))
+	}
+	if len(s.conversion) > 0 {
+		options = append(append([]flat.Option{}, options...), flat.WithConversion(s.conversion...))
+	}
 	flatExecutor, err := flat.NewExecutor(s, options...)
 	if err != nil {
 		return NewNilStream(newStreamError(errors.Flat, errMsgCannotCreateExecutor, err))
 	}
-	return NewStream(flatExecutor.Execute())
+	return s.newStream(flatExecutor.Execute(), "flat")
+}
+
+func (s *stream) FlatMap(mapperFunc interface{}, options ...flat.Option) Stream {
+	mapped := s.Map(mapperFunc)
+	if err := mapped.Err(); err != nil {
+		return mapped
+	}
+	return mapped.Flat(options...)
 }
 
 func (s *stream) Lift(options ...lift.Option) Stream {
+	if s.logger != nil {
+		options = append(append([]lift.Option{}, options...), lift.WithLogger(s.logger))
+	}
+	if len(s.conversion) > 0 {
+		options = append(append([]lift.Option{}, options...), lift.WithConversion(s.conversion...))
+	}
 	var err error
 	liftExecutor, err := lift.NewExecutor(s, options...)
 	if err != nil {
@@ -191,5 +463,112 @@ func (s *stream) Lift(options ...lift.Option) Stream {
 	if err != nil {
 		return NewNilStream(newStreamError(errors.Lift, errMsgCannotExecute, err))
 	}
-	return NewStream(iter)
+	return s.newStream(iter, "lift")
+}
+
+func (s *stream) Throttle(ratePerSecond float64, burst int, options ...throttle.Option) Stream {
+	if s.logger != nil {
+		options = append(append([]throttle.Option{}, options...), throttle.WithLogger(s.logger))
+	}
+	if len(s.conversion) > 0 {
+		options = append(append([]throttle.Option{}, options...), throttle.WithConversion(s.conversion...))
+	}
+	throttleExecutor, err := throttle.NewExecutor(s, ratePerSecond, burst, options...)
+	if err != nil {
+		return NewNilStream(newStreamError(errors.Throttle, errMsgCannotCreateExecutor, err))
+	}
+	return s.newStream(throttleExecutor.Execute(), "throttle")
+}
+
+func (s *stream) Sample(interval time.Duration, options ...sample.Option) Stream {
+	if s.logger != nil {
+		options = append(append([]sample.Option{}, options...), sample.WithLogger(s.logger))
+	}
+	if len(s.conversion) > 0 {
+		options = append(append([]sample.Option{}, options...), sample.WithConversion(s.conversion...))
+	}
+	sampleExecutor, err := sample.NewExecutor(s, interval, options...)
+	if err != nil {
+		return NewNilStream(newStreamError(errors.Sample, errMsgCannotCreateExecutor, err))
+	}
+	return s.newStream(sampleExecutor.Execute(), "sample")
+}
+
+func (s *stream) SampleReservoir(k int, options ...sample.Option) Stream {
+	if s.logger != nil {
+		options = append(append([]sample.Option{}, options...), sample.WithLogger(s.logger))
+	}
+	if len(s.conversion) > 0 {
+		options = append(append([]sample.Option{}, options...), sample.WithConversion(s.conversion...))
+	}
+	var err error
+	sampleExecutor, err := sample.NewReservoirExecutor(s, k, options...)
+	if err != nil {
+		return NewNilStream(newStreamError(errors.Sample, errMsgCannotCreateExecutor, err))
+	}
+	return s.newStream(sampleExecutor.Execute(), "sample")
+}
+
+func (s *stream) SampleFraction(p float64, options ...sample.Option) Stream {
+	if s.logger != nil {
+		options = append(append([]sample.Option{}, options...), sample.WithLogger(s.logger))
+	}
+	if len(s.conversion) > 0 {
+		options = append(append([]sample.Option{}, options...), sample.WithConversion(s.conversion...))
+	}
+	var err error
+	sampleExecutor, err := sample.NewFractionExecutor(s, p, options...)
+	if err != nil {
+		return NewNilStream(newStreamError(errors.Sample, errMsgCannotCreateExecutor, err))
+	}
+	return s.newStream(sampleExecutor.Execute(), "sample")
+}
+
+func (s *stream) SampleStratified(key interface{}, options ...sample.Option) Stream {
+	if s.logger != nil {
+		options = append(append([]sample.Option{}, options...), sample.WithLogger(s.logger))
+	}
+	if len(s.conversion) > 0 {
+		options = append(append([]sample.Option{}, options...), sample.WithConversion(s.conversion...))
+	}
+	var err error
+	sampleExecutor, err := sample.NewStratifiedExecutor(s, key, options...)
+	if err != nil {
+		return NewNilStream(newStreamError(errors.Sample, errMsgCannotCreateExecutor, err))
+	}
+	return s.newStream(sampleExecutor.Execute(), "sample")
+}
+
+func (s *stream) Peek(f interface{}, options ...peek.Option) Stream {
+	if s.logger != nil {
+		options = append(append([]peek.Option{}, options...), peek.WithLogger(s.logger))
+	}
+	if len(s.conversion) > 0 {
+		options = append(append([]peek.Option{}, options...), peek.WithConversion(s.conversion...))
+	}
+	var err error
+	c, err := consume.NewConsumer(f)
+	if err != nil {
+		return NewNilStream(newStreamError(errors.Peek, errMsgInvalidFunction, err))
+	}
+	peekExecutor, err := peek.NewExecutor(c, s, options...)
+	if err != nil {
+		return NewNilStream(newStreamError(errors.Peek, errMsgCannotCreateExecutor, err))
+	}
+	return s.newStream(peekExecutor.Execute(), "peek")
+}
+
+func (s *stream) Debug(logger log.Logger, label string, options ...peek.Option) Stream {
+	if s.logger != nil {
+		options = append(append([]peek.Option{}, options...), peek.WithLogger(s.logger))
+	}
+	if len(s.conversion) > 0 {
+		options = append(append([]peek.Option{}, options...), peek.WithConversion(s.conversion...))
+	}
+	var err error
+	peekExecutor, err := peek.NewDebugExecutor(logger, label, s, options...)
+	if err != nil {
+		return NewNilStream(newStreamError(errors.Peek, errMsgCannotCreateExecutor, err))
+	}
+	return s.newStream(peekExecutor.Execute(), "debug")
 }
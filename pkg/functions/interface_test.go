@@ -1,17 +1,30 @@
 package functions_test
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"sort"
 	"strings"
 	"testing"
+	"time"
+	"tools/pkg/clock"
+	"tools/pkg/conv/reflection"
+	"tools/pkg/errors"
 	"tools/pkg/functions"
+	"tools/pkg/functions/consume"
 	"tools/pkg/functions/executor"
 	"tools/pkg/functions/flat"
 	"tools/pkg/functions/fold"
 	"tools/pkg/functions/iterator"
+	"tools/pkg/functions/lift"
 	"tools/pkg/functions/mapper"
+	"tools/pkg/functions/peek"
+	"tools/pkg/functions/sample"
+	"tools/pkg/functions/sorter"
+	"tools/pkg/functions/throttle"
+	"tools/pkg/log"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -150,6 +163,62 @@ func TestStream(t *testing.T) {
 			},
 			Result: []interface{}{people()},
 		},
+		&streamTestcase{
+			Comment: "lift-widen-int",
+			Data:    []interface{}{int8(1), int16(2), uint8(3)},
+			Stream: func(s functions.Stream) functions.Stream {
+				return s.Lift()
+			},
+			Result: []interface{}{[]int16{1, 2, 3}},
+		},
+		&streamTestcase{
+			Comment: "lift-widen-float",
+			Data:    []interface{}{1, float32(1.5)},
+			Stream: func(s functions.Stream) functions.Stream {
+				return s.Lift()
+			},
+			Result: []interface{}{[]float64{1, 1.5}},
+		},
+		&streamTestcase{
+			Comment: "lift-widen-float-overflow",
+			Data:    []interface{}{int64(1<<53 + 1), 1.5},
+			Stream: func(s functions.Stream) functions.Stream {
+				return s.Lift()
+			},
+			Result: []interface{}{[]interface{}{int64(1<<53 + 1), 1.5}},
+		},
+		&streamTestcase{
+			Comment: "lift-interface",
+			Data:    []interface{}{time.Second, time.Month(1)},
+			Stream: func(s functions.Stream) functions.Stream {
+				return s.Lift()
+			},
+			Result: []interface{}{[]fmt.Stringer{time.Second, time.Month(1)}},
+		},
+		&streamTestcase{
+			Comment: "lift-nil",
+			Data:    []interface{}{&Person{Name: "Aud"}, nil},
+			Stream: func(s functions.Stream) functions.Stream {
+				return s.Lift()
+			},
+			Result: []interface{}{[]*Person{{Name: "Aud"}, nil}},
+		},
+		&streamTestcase{
+			Comment: "lift-nil-scalar",
+			Data:    []interface{}{1, nil, "a"},
+			Stream: func(s functions.Stream) functions.Stream {
+				return s.Lift()
+			},
+			Result: []interface{}{[]interface{}{1, nil, "a"}},
+		},
+		&streamTestcase{
+			Comment: "lift-type",
+			Data:    []int{1, 2},
+			Stream: func(s functions.Stream) functions.Stream {
+				return s.Lift(lift.WithType(reflect.TypeOf(float32(0))))
+			},
+			Result: []interface{}{[]float32{1, 2}},
+		},
 		&streamTestcase{
 			Comment: "lift-flat-people",
 			Data:    people(),
@@ -244,6 +313,89 @@ func TestStream(t *testing.T) {
 				1, 2, 3, 4, 5, 6,
 			},
 		},
+		&streamTestcase{
+			Comment: "flat-depth",
+			Data: [][][][]int{
+				[][][]int{
+					[][]int{
+						[]int{1, 2},
+					},
+				},
+				[][][]int{
+					[][]int{
+						[]int{3},
+						[]int{4, 5},
+					},
+				},
+			},
+			Stream: func(s functions.Stream) functions.Stream {
+				return s.Flat(flat.WithDepth(2))
+			},
+			Result: []interface{}{
+				[]int{1, 2}, []int{3}, []int{4, 5},
+			},
+		},
+		&streamTestcase{
+			Comment: "flat-map-values",
+			Data: []interface{}{
+				map[string][]int{"a": []int{1, 2}},
+				map[string][]int{"b": []int{3}},
+			},
+			Stream: func(s functions.Stream) functions.Stream {
+				return s.Flat(flat.WithType(flat.TypePerfect), flat.WithMapMode(flat.MapValues))
+			},
+			Result: []interface{}{
+				1, 2, 3,
+			},
+		},
+		&streamTestcase{
+			Comment: "flat-map-keys",
+			Data: []interface{}{
+				map[string]int{"a": 1},
+				map[string]int{"b": 2},
+			},
+			Stream: func(s functions.Stream) functions.Stream {
+				return s.Flat(flat.WithMapMode(flat.MapKeys))
+			},
+			Result: []interface{}{
+				"a", "b",
+			},
+		},
+		&streamTestcase{
+			Comment: "flat-bytes-scalar",
+			Data: []interface{}{
+				[][]byte{[]byte("ab")},
+				[]byte("c"),
+			},
+			Stream: func(s functions.Stream) functions.Stream {
+				return s.Flat(flat.WithType(flat.TypePerfect), flat.WithBytesAsScalar())
+			},
+			Result: []interface{}{
+				[]byte("ab"), []byte("c"),
+			},
+		},
+		&streamTestcase{
+			Comment: "flat-bytes",
+			Data: []interface{}{
+				[]byte("ab"),
+			},
+			Stream: func(s functions.Stream) functions.Stream {
+				return s.Flat()
+			},
+			Result: []interface{}{
+				byte('a'), byte('b'),
+			},
+		},
+		&streamTestcase{
+			Comment: "flatmap",
+			Data:    []string{"a b", "c"},
+			Stream: func(s functions.Stream) functions.Stream {
+				return s.FlatMap(strings.Fields)
+			},
+			Result: []interface{}{
+				"a", "b", "c",
+			},
+		},
 		&streamTestcase{
 			Comment: "flat-perfect-interface",
 			Data: []interface{}{
@@ -306,6 +458,91 @@ func TestStream(t *testing.T) {
 				return ret
 			}(),
 		},
+		&streamTestcase{
+			Comment: "sort-comparator",
+			Data:    []int{5, 4, 9, 2},
+			Stream: func(s functions.Stream) functions.Stream {
+				return s.Sort(func(x, y int) int {
+					return y - x
+				})
+			},
+			Result: []interface{}{9, 5, 4, 2},
+		},
+		&streamTestcase{
+			Comment: "sortby-people",
+			Data:    people(),
+			Stream: func(s functions.Stream) functions.Stream {
+				return s.SortBy(func(x Person) string {
+					return x.Region
+				}, sorter.Desc(func(x Person) string {
+					return x.Name
+				}))
+			},
+			Result: func() []interface{} {
+				ps := people()
+				sort.SliceStable(ps, func(i, j int) bool {
+					if ps[i].Region != ps[j].Region {
+						return ps[i].Region < ps[j].Region
+					}
+					return ps[i].Name > ps[j].Name
+				})
+				ret := make([]interface{}, len(ps))
+				for i, p := range ps {
+					ret[i] = p
+				}
+				return ret
+			}(),
+		},
+		&streamTestcase{
+			Comment: "sortby-stable",
+			Data:    []string{"bb", "a", "cc", "d"},
+			Stream: func(s functions.Stream) functions.Stream {
+				return s.SortBy(sorter.Desc(func(x string) int {
+					return len(x)
+				}))
+			},
+			Result: []interface{}{"bb", "cc", "a", "d"},
+		},
+		&streamTestcase{
+			Comment: "topk",
+			Data:    []int{5, 4, 9, 2, 7, 9},
+			Stream: func(s functions.Stream) functions.Stream {
+				return s.TopK(3, func(x, y int) bool {
+					return x < y
+				})
+			},
+			Result: []interface{}{9, 9, 7},
+		},
+		&streamTestcase{
+			Comment: "topk-more-than-stream",
+			Data:    []int{5, 4},
+			Stream: func(s functions.Stream) functions.Stream {
+				return s.TopK(3, func(x, y int) bool {
+					return x < y
+				})
+			},
+			Result: []interface{}{5, 4},
+		},
+		&streamTestcase{
+			Comment: "topk-stable",
+			Data:    []string{"a", "bb", "c", "dd", "e", "f"},
+			Stream: func(s functions.Stream) functions.Stream {
+				return s.TopK(4, func(x, y string) bool {
+					return len(x) < len(y)
+				})
+			},
+			Result: []interface{}{"bb", "dd", "a", "c"},
+		},
+		&streamTestcase{
+			Comment: "bottomk-stable",
+			Data:    []string{"aa", "b", "cc", "d", "ee", "f"},
+			Stream: func(s functions.Stream) functions.Stream {
+				return s.BottomK(4, func(x, y string) int {
+					return len(x) - len(y)
+				})
+			},
+			Result: []interface{}{"b", "d", "f", "aa"},
+		},
 		&streamTestcase{
 			Comment: "filter-no-result",
 			Data:    people(),
@@ -382,6 +619,52 @@ func TestStream(t *testing.T) {
 				return []interface{}{r}
 			}(),
 		},
+		&streamTestcase{
+			Comment: "aggregate-tree",
+			Data:    []int{1, 2, 3, 4, 5},
+			Stream: func(s functions.Stream) functions.Stream {
+				return s.Fold(func(x, y int) int {
+					return x + y
+				}, fold.WithType(fold.TypeT))
+			},
+			Result: []interface{}{15},
+		},
+		&streamTestcase{
+			Comment: "aggregate-parallel",
+			Data: func() []int {
+				r := make([]int, 1000)
+				for i := range r {
+					r[i] = i + 1
+				}
+				return r
+			}(),
+			Stream: func(s functions.Stream) functions.Stream {
+				return s.Fold(func(x, y int) int {
+					return x + y
+				}, fold.WithType(fold.TypeParallel), fold.WithChunkSize(7), fold.WithWorkers(4))
+			},
+			Result: []interface{}{500500},
+		},
+		&streamTestcase{
+			Comment: "aggregate-parallel-deterministic",
+			Data:    strings.Split("abcdefghijklmnopqrstuvwxyz", ""),
+			Stream: func(s functions.Stream) functions.Stream {
+				return s.Fold(func(x, y string) string {
+					return x + y
+				}, fold.WithType(fold.TypeParallel), fold.WithChunkSize(3), fold.WithWorkers(4), fold.WithDeterministic())
+			},
+			Result: []interface{}{"abcdefghijklmnopqrstuvwxyz"},
+		},
+		&streamTestcase{
+			Comment: "aggregate-parallel-empty",
+			Data:    []int{},
+			Stream: func(s functions.Stream) functions.Stream {
+				return s.Fold(func(x, y int) int {
+					return x + y
+				}, fold.WithType(fold.TypeParallel), fold.WithInitialValue(-1))
+			},
+			Result: []interface{}{-1},
+		},
 		&streamTestcase{
 			Comment: "mix",
 			Data:    people(),
@@ -614,6 +897,99 @@ func TestStreamAs(t *testing.T) {
 				}
 			},
 		},
+		{
+			Comment: "people-from-maps",
+			Test: func(t *testing.T) {
+				var (
+					d = []map[string]interface{}{
+						{"name": "Stela", "surname": "Sandu", "region": "Romania"},
+						{"name": "Aud"},
+					}
+					r []*Person
+				)
+				if err := convert(d).As(&r); err != nil {
+					t.Error(err)
+				}
+				expected := []*Person{
+					{Name: "Stela", Surname: "Sandu", Region: "Romania"},
+					{Name: "Aud"},
+				}
+				if !cmp.Equal(r, expected) {
+					t.Errorf("got %#v", r)
+				}
+			},
+		},
+		{
+			Comment: "array",
+			Test: func(t *testing.T) {
+				var r [3]int
+				if err := convert([]int{1, 2}).As(&r); err != nil {
+					t.Error(err)
+				}
+				if !cmp.Equal(r, [3]int{1, 2, 0}) {
+					t.Errorf("got %#v", r)
+				}
+				if err := convert([]int{1, 2, 3, 4}).As(&r); err == nil {
+					t.Errorf("should be error, got %#v", r)
+				}
+			},
+		},
+		{
+			Comment: "strict",
+			Test: func(t *testing.T) {
+				var r []int8
+				st := functions.NewStream(iterator.MustNew([]int{1, 2, 300}), functions.WithStrictConversion()).Map(func(x int) int {
+					return x
+				})
+				err := st.As(&r)
+				if err == nil {
+					t.Fatalf("should be error, got %#v", r)
+				}
+				if !strings.Contains(err.Error(), "overflow") {
+					t.Errorf("not expected error: %v", err)
+				}
+				if err := functions.NewStream(iterator.MustNew([]int{1, 2, 300})).As(&r); err != nil {
+					t.Error(err)
+				}
+				if err := functions.NewStream(iterator.MustNew([]float64{1.5})).As(&r, reflection.WithStrict()); err == nil {
+					t.Errorf("should be error, got %#v", r)
+				}
+			},
+		},
+		{
+			Comment: "strict-fold",
+			Test: func(t *testing.T) {
+				sum := func(acc int, x int) int { return acc + x }
+				err := functions.NewStream(iterator.MustNew([]float64{1.5, 2.5}), functions.WithStrictConversion()).Fold(sum).Err()
+				if err == nil || !strings.Contains(err.Error(), "fractional") {
+					t.Errorf("should be error of fractional argument, got %v", err)
+				}
+				var r []int
+				if err := functions.NewStream(iterator.MustNew([]float64{1, 2}), functions.WithStrictConversion()).Fold(sum).As(&r); err != nil {
+					t.Error(err)
+				}
+				if !cmp.Equal(r, []int{3}) {
+					t.Errorf("got %#v", r)
+				}
+			},
+		},
+		{
+			Comment: "strict-hook",
+			Test: func(t *testing.T) {
+				var hooked []int
+				st := functions.NewStream(iterator.MustNew([]float64{1, 2.5}), functions.WithStrictConversion()).Map(func(x float64) float64 {
+					return x
+				}, mapper.WithHook(executor.RunningHook, func(x int) {
+					hooked = append(hooked, x)
+				}))
+				if _, err := iterator.ToSlice(st); err != nil {
+					t.Fatal(err)
+				}
+				if !cmp.Equal(hooked, []int{1}) {
+					t.Errorf("lossy argument should not be passed to hook, got %v", hooked)
+				}
+			},
+		},
 	}
 
 	for _, tt := range testcases {
@@ -681,3 +1057,476 @@ func TestStreamComplex(t *testing.T) {
 		}
 	})
 }
+
+func TestStreamThrottle(t *testing.T) {
+	testcases := []struct {
+		Comment string
+		Rate    float64
+		Burst   int
+		Data    []int
+		Slept   time.Duration
+	}{
+		{
+			Comment: "no-content",
+			Rate:    1,
+			Burst:   1,
+			Data:    []int{},
+			Slept:   0,
+		},
+		{
+			Comment: "within-burst",
+			Rate:    1,
+			Burst:   3,
+			Data:    []int{1, 2, 3},
+			Slept:   0,
+		},
+		{
+			Comment: "over-burst",
+			Rate:    2,
+			Burst:   2,
+			Data:    []int{1, 2, 3, 4, 5},
+			Slept:   1500 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.Comment, func(t *testing.T) {
+			c := clock.NewFake(time.Unix(0, 0))
+			var (
+				r       []int
+				waited  time.Duration
+				results []interface{}
+			)
+			if err := functions.NewStream(iterator.MustNew(tt.Data)).Throttle(tt.Rate, tt.Burst,
+				throttle.WithClock(c),
+				throttle.WithOnWait(func(d time.Duration) { waited += d }),
+				throttle.WithHook(executor.RunningResultHook, func(x interface{}) { results = append(results, x) }),
+			).As(&r); err != nil {
+				t.Error(err)
+			}
+			if waited != tt.Slept {
+				t.Errorf("waited %v expected %v", waited, tt.Slept)
+			}
+			if len(results) != len(tt.Data) || (len(results) > 0 && results[0] != tt.Data[0]) {
+				t.Errorf("not expected running results: %v", results)
+			}
+			if !cmp.Equal(r, tt.Data) {
+				t.Errorf("  actual: %v\nexpected: %v", r, tt.Data)
+			}
+			if c.Slept() != tt.Slept {
+				t.Errorf("slept %v expected %v", c.Slept(), tt.Slept)
+			}
+		})
+	}
+}
+
+func TestStreamSample(t *testing.T) {
+	type arrival struct {
+		After time.Duration
+		Value int
+	}
+	testcases := []struct {
+		Comment  string
+		Interval time.Duration
+		Data     []arrival
+		Result   []int
+	}{
+		{
+			Comment:  "no-content",
+			Interval: time.Second,
+			Data:     []arrival{},
+			Result:   []int{},
+		},
+		{
+			Comment:  "single",
+			Interval: time.Second,
+			Data: []arrival{
+				{After: 0, Value: 1},
+			},
+			Result: []int{1},
+		},
+		{
+			Comment:  "latest-per-interval",
+			Interval: time.Second,
+			Data: []arrival{
+				{After: 0, Value: 1},
+				{After: 100 * time.Millisecond, Value: 2},
+				{After: 100 * time.Millisecond, Value: 3},
+				{After: 800 * time.Millisecond, Value: 4},
+				{After: 100 * time.Millisecond, Value: 5},
+				{After: 1400 * time.Millisecond, Value: 6},
+			},
+			Result: []int{3, 5, 6},
+		},
+		{
+			Comment:  "long-stall",
+			Interval: time.Nanosecond,
+			Data: []arrival{
+				{After: 0, Value: 1},
+				{After: 100 * 24 * time.Hour, Value: 2},
+				{After: 0, Value: 3},
+			},
+			Result: []int{1, 3},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.Comment, func(t *testing.T) {
+			var (
+				c = clock.NewFake(time.Unix(0, 0))
+				i int
+				r = []int{}
+			)
+			src := iterator.MustNew(iterator.Func(func() (interface{}, error) {
+				if i >= len(tt.Data) {
+					return nil, iterator.EOI
+				}
+				x := tt.Data[i]
+				i++
+				c.Advance(x.After)
+				return x.Value, nil
+			}))
+			if err := functions.NewStream(src).Sample(tt.Interval, sample.WithClock(c)).As(&r); err != nil {
+				t.Error(err)
+			}
+			if !cmp.Equal(r, tt.Result) {
+				t.Errorf("  actual: %v\nexpected: %v", r, tt.Result)
+			}
+		})
+	}
+}
+
+func TestStreamSampleRandom(t *testing.T) {
+	var (
+		seq = func(n int) []int {
+			r := make([]int, n)
+			for i := range r {
+				r[i] = i
+			}
+			return r
+		}
+		ascending = func(r []int) bool {
+			for i := 1; i < len(r); i++ {
+				if r[i-1] >= r[i] {
+					return false
+				}
+			}
+			return true
+		}
+		mod3 = func(x int) int { return x % 3 }
+	)
+	testcases := []struct {
+		Comment string
+		Sample  func(st functions.Stream, seed int64) functions.Stream
+		Data    []int
+		Check   func(r []int) bool
+		Error   string
+	}{
+		{
+			Comment: "reservoir",
+			Sample: func(st functions.Stream, seed int64) functions.Stream {
+				return st.SampleReservoir(5, sample.WithSeed(seed))
+			},
+			Data:  seq(100),
+			Check: func(r []int) bool { return len(r) == 5 && ascending(r) },
+		},
+		{
+			Comment: "reservoir-less-than-k",
+			Sample: func(st functions.Stream, seed int64) functions.Stream {
+				return st.SampleReservoir(5, sample.WithSeed(seed))
+			},
+			Data:  seq(3),
+			Check: func(r []int) bool { return cmp.Equal(r, seq(3)) },
+		},
+		{
+			Comment: "reservoir-invalid-k",
+			Sample: func(st functions.Stream, seed int64) functions.Stream {
+				return st.SampleReservoir(0, sample.WithSeed(seed))
+			},
+			Data:  seq(3),
+			Error: "k must be positive",
+		},
+		{
+			Comment: "fraction",
+			Sample: func(st functions.Stream, seed int64) functions.Stream {
+				return st.SampleFraction(0.3, sample.WithSeed(seed))
+			},
+			Data:  seq(10000),
+			Check: func(r []int) bool { return len(r) > 2800 && len(r) < 3200 && ascending(r) },
+		},
+		{
+			Comment: "fraction-all",
+			Sample: func(st functions.Stream, seed int64) functions.Stream {
+				return st.SampleFraction(1, sample.WithSeed(seed))
+			},
+			Data:  seq(10),
+			Check: func(r []int) bool { return cmp.Equal(r, seq(10)) },
+		},
+		{
+			Comment: "fraction-invalid",
+			Sample: func(st functions.Stream, seed int64) functions.Stream {
+				return st.SampleFraction(1.5, sample.WithSeed(seed))
+			},
+			Data:  seq(10),
+			Error: "fraction must be in [0, 1]",
+		},
+		{
+			Comment: "stratified",
+			Sample: func(st functions.Stream, seed int64) functions.Stream {
+				return st.SampleStratified(mod3, sample.WithStratumSize(2), sample.WithSeed(seed))
+			},
+			Data: seq(100),
+			Check: func(r []int) bool {
+				count := map[int]int{}
+				for _, x := range r {
+					count[x%3]++
+				}
+				return len(r) == 6 && ascending(r) && cmp.Equal(count, map[int]int{0: 2, 1: 2, 2: 2})
+			},
+		},
+		{
+			Comment: "stratified-invalid-key",
+			Sample: func(st functions.Stream, seed int64) functions.Stream {
+				return st.SampleStratified(func(x int) []int { return nil }, sample.WithStratumSize(2), sample.WithSeed(seed))
+			},
+			Data:  seq(10),
+			Error: "key must be a -> k",
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.Comment, func(t *testing.T) {
+			var results [][]int
+			for _, seed := range []int64{42, 42} {
+				var (
+					r  = []int{}
+					st = tt.Sample(functions.NewStream(iterator.MustNew(tt.Data)), seed)
+				)
+				if tt.Error != "" {
+					if err := st.Err(); err == nil || !strings.Contains(err.Error(), tt.Error) {
+						t.Errorf("not expected error: %v", err)
+					}
+					return
+				}
+				if err := st.As(&r); err != nil {
+					t.Fatal(err)
+				}
+				if !tt.Check(r) {
+					t.Errorf("not expected sample: %v", r)
+				}
+				results = append(results, r)
+			}
+			if !cmp.Equal(results[0], results[1]) {
+				t.Errorf("not reproducible: %v and %v", results[0], results[1])
+			}
+		})
+	}
+
+	t.Run("stratified-not-comparable-key", func(t *testing.T) {
+		key := func(x int) interface{} {
+			if x == 1 {
+				return []int{x}
+			}
+			return x % 2
+		}
+		st := functions.NewStream(iterator.MustNew(seq(4))).SampleStratified(key, sample.WithStratumSize(2))
+		if _, err := iterator.ToSlice(st); err == nil || !strings.Contains(err.Error(), "key is not comparable") {
+			t.Errorf("not expected error: %v", err)
+		}
+		var r []int
+		st = functions.NewStream(iterator.MustNew(seq(4)), functions.WithErrorPolicy(functions.ErrorSkip)).SampleStratified(key, sample.WithStratumSize(2))
+		if err := st.As(&r); err != nil {
+			t.Fatal(err)
+		}
+		if !cmp.Equal(r, []int{0, 2, 3}) {
+			t.Errorf("not expected sample: %v", r)
+		}
+		if err := st.Err(); err == nil || !strings.Contains(err.Error(), "key is not comparable") {
+			t.Errorf("not expected error: %v", err)
+		}
+	})
+}
+
+func TestStreamErrorCause(t *testing.T) {
+	err := functions.NewStream(iterator.MustNew([]int{1})).Map(1).Err()
+	if !errors.Is(err, mapper.InvalidMapper) {
+		t.Errorf("should be InvalidMapper: %v", err)
+	}
+	if c := errors.CodeOf(err); c != errors.Map {
+		t.Errorf("not expected code: %v", c)
+	}
+
+	for _, depth := range []int{0, -1} {
+		err = functions.NewStream(iterator.MustNew([]int{1})).Flat(flat.WithDepth(depth)).Err()
+		if !errors.Is(err, flat.InvalidDepth) {
+			t.Errorf("should be InvalidDepth by depth %d: %v", depth, err)
+		}
+		if !errors.Is(err, errors.NewError().SetCode(errors.Validate)) {
+			t.Errorf("should be caused by validation by depth %d: %v", depth, err)
+		}
+	}
+
+	err = functions.NewStream(iterator.MustNew([]string{"x"})).Fold(func(x, acc int) int { return x + acc }).Err()
+	if !errors.Is(err, errors.NewError().SetCode(errors.Conversion)) {
+		t.Errorf("should be caused by conversion: %v", err)
+	}
+}
+
+func TestStreamErrorSkip(t *testing.T) {
+	var (
+		src = strings.NewReader("{\"n\": 1}\n{\"n\": \"x\"}\n{\"n\": 3\n{\"n\": 4}\n")
+		st  = functions.NewStream(functions.NewJSONLinesSourceStream(src), functions.WithErrorPolicy(functions.ErrorSkip)).
+			Map(func(x map[string]interface{}) interface{} { return x["n"] }).
+			Map(func(x float64) float64 { return x * 2 })
+		r = []float64{}
+	)
+	if err := st.As(&r); err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(r, []float64{2, 8}) {
+		t.Errorf("not expected result: %v", r)
+	}
+	var m *errors.MultiError
+	if err := st.Err(); !errors.As(err, &m) {
+		t.Fatalf("should be MultiError: %v", err)
+	}
+	groups := m.Groups()
+	if m.Len() != 2 || len(groups) != 2 {
+		t.Fatalf("not expected errors: %v", m)
+	}
+	if g := groups[0]; g.Code != errors.Conversion || g.Examples[0].Value != "x" || g.Examples[0].Index != 2 {
+		t.Errorf("not expected conversion error: %v", m)
+	}
+	if g := groups[1]; g.Code != errors.Parse || g.Examples[0].Line != 3 || g.Examples[0].Value != `{"n": 3` {
+		t.Errorf("not expected parse error: %v", m)
+	}
+
+	if err := functions.NewStream(iterator.MustNew([]int{1}), functions.WithErrorPolicy(functions.ErrorStop)).Err(); err != nil {
+		t.Errorf("not expected error: %v", err)
+	}
+	if err := functions.NewStream(iterator.MustNew([]int{1}), functions.WithErrorPolicy(functions.UnknownErrorPolicy)).Err(); err == nil {
+		t.Error("should be error for unknown policy")
+	}
+}
+
+func TestStreamLogger(t *testing.T) {
+	var (
+		buf bytes.Buffer
+		l   = log.NewLogger(log.WithWriter(&buf), log.WithLevel(log.Debug))
+		r   = []int{}
+	)
+	st := functions.NewStream(iterator.MustNew([]interface{}{1, "x", 3}), functions.WithLogger(l), functions.WithErrorPolicy(functions.ErrorSkip)).
+		Map(func(x int) int { return x * 2 }).
+		Filter(func(x int) bool { return x > 2 })
+	if err := st.As(&r); err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(r, []int{6}) {
+		t.Errorf("not expected result: %v", r)
+	}
+	expected := strings.Join([]string{
+		"D | start stage=map",
+		"D | start stage=filter",
+		`W | skipped: Conversion invalid argument for mapper: Conversion invalid conversion: strconv.ParseInt: parsing "x": invalid syntax index=2`,
+		"D | end: 3 elements stage=map",
+		"D | end: 2 elements stage=filter",
+		"",
+	}, "\n")
+	if got := buf.String(); got != expected {
+		t.Errorf("  actual: %s\nexpected: %s", got, expected)
+	}
+
+	buf.Reset()
+	var n []int
+	if err := functions.NewStream(iterator.MustNew([]int{3, 1, 2}), functions.WithLogger(l)).
+		Sort(func(x, y int) bool { return x < y }).
+		SortBy(func(x int) int { return -x }).
+		TopK(2, func(x, y int) bool { return x < y }).
+		Fold(func(acc, x int) int { return acc + x }).
+		As(&n); err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(n, []int{5}) {
+		t.Errorf("not expected result: %v", n)
+	}
+	if !strings.Contains(buf.String(), "D | end: 2 elements stage=fold") {
+		t.Errorf("not expected logs of fold stage: %s", buf.String())
+	}
+	if got := strings.Count(buf.String(), "D | end: 3 elements stage=sort"); got != 3 {
+		t.Errorf("not expected logs of sort stages: %s", buf.String())
+	}
+}
+
+func TestStreamPeek(t *testing.T) {
+	var (
+		peeked = []int{}
+		r      = []int{}
+	)
+	if err := functions.NewStream(iterator.MustNew([]int{1, 2, 3, 4, 5})).
+		Peek(func(x int) { peeked = append(peeked, x) }, peek.WithEvery(2)).
+		As(&r); err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(r, []int{1, 2, 3, 4, 5}) || !cmp.Equal(peeked, []int{1, 3, 5}) {
+		t.Errorf("not expected result: %v peeked: %v", r, peeked)
+	}
+
+	if err := functions.NewStream(iterator.MustNew([]int{1})).Peek(func(x int) int { return x }).Err(); !errors.Is(err, consume.InvalidConsumer) {
+		t.Errorf("not expected error: %v", err)
+	}
+}
+
+func TestStreamDebug(t *testing.T) {
+	var (
+		buf bytes.Buffer
+		l   = log.NewLogger(log.WithWriter(&buf), log.WithLevel(log.Debug))
+		r   = []interface{}{}
+	)
+	if err := functions.NewStream(iterator.MustNew([]interface{}{"abcdef", 2, []int{3}})).
+		Debug(l, "src", peek.WithMaxLength(3)).
+		As(&r); err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(r, []interface{}{"abcdef", 2, []int{3}}) {
+		t.Errorf("not expected result: %v", r)
+	}
+	expected := strings.Join([]string{
+		"D | abc... label=src index=1 type=string",
+		"D | 2 label=src index=2 type=int",
+		"D | [3] label=src index=3 type=[]int",
+		"",
+	}, "\n")
+	if got := buf.String(); got != expected {
+		t.Errorf("  actual: %s\nexpected: %s", got, expected)
+	}
+
+	if err := functions.NewStream(iterator.MustNew([]int{1})).Debug(nil, "x").Err(); !errors.Is(err, peek.InvalidLogger) {
+		t.Errorf("not expected error: %v", err)
+	}
+}
+
+func TestDebugTap(t *testing.T) {
+	var buf bytes.Buffer
+	functions.SetDebugTap(log.NewLogger(log.WithWriter(&buf), log.WithLevel(log.Debug)))
+	defer functions.SetDebugTap(nil)
+
+	r := []int{}
+	if err := functions.NewStream(iterator.MustNew([]int{1, 2})).
+		Map(func(x int) int { return x * 10 }).
+		Filter(func(x int) bool { return x > 10 }).
+		As(&r); err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(r, []int{20}) {
+		t.Errorf("not expected result: %v", r)
+	}
+	expected := strings.Join([]string{
+		"D | 10 label=map index=1 type=int",
+		"D | 20 label=map index=2 type=int",
+		"D | 20 label=filter index=1 type=int",
+		"",
+	}, "\n")
+	if got := buf.String(); got != expected {
+		t.Errorf("  actual: %s\nexpected: %s", got, expected)
+	}
+}
@@ -24,7 +24,7 @@ func ToChan(iter Iterator) (<-chan IE, errors.Error) {
 	go func() {
 		for {
 			x, err := iter.Next()
-			if err == EOI {
+			if errors.Is(err, EOI) {
 				close(ch)
 				return
 			}
@@ -44,7 +44,7 @@ func ToSlice(iter Iterator) ([]interface{}, errors.Error) {
 	ret := []interface{}{}
 	for {
 		x, err := iter.Next()
-		if err == EOI {
+		if errors.Is(err, EOI) {
 			return ret, nil
 		}
 		if err != nil {
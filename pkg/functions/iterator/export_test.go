@@ -0,0 +1,15 @@
+package iterator
+
+// CursorsOf returns the number of cursors holding the spilled file of a replayable or cyclic iterator
+func CursorsOf(iter Iterator) int {
+	var c *replayCache
+	switch x := iter.(type) {
+	case *replayCursor:
+		c = x.cache
+	case *cyclicIterator:
+		c = x.r.cache
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return len(c.cursors)
+}
@@ -120,7 +120,8 @@ func (s *funcIterator) Next() (interface{}, error) {
 	}
 	x, err := s.f()
 	if err != nil {
-		s.isEOI = true
+		// yields the next element after an error of an element
+		s.isEOI = !errors.IsElementError(err)
 		return nil, err
 	}
 	return x, nil
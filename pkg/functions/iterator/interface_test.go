@@ -76,3 +76,114 @@ func TestIterator(t *testing.T) {
 		})
 	}
 }
+
+func TestPeekable(t *testing.T) {
+	p := iterator.NewPeekable(iterator.MustNew([]int{1, 2, 3}))
+	if x, err := p.Peek(); err != nil || x != 1 {
+		t.Errorf("peek got %v %v", x, err)
+	}
+	if x, err := p.Next(); err != nil || x != 1 {
+		t.Errorf("next got %v %v", x, err)
+	}
+	p.Unread(10)
+	p.Unread(20)
+	ret, err := iterator.ToSlice(p)
+	if err != nil {
+		t.Error(err)
+	}
+	expected := []interface{}{20, 10, 2, 3}
+	if !cmp.Equal(ret, expected) {
+		t.Errorf("  actual: %v\nexpected: %v", ret, expected)
+	}
+	if _, err := p.Peek(); err != iterator.EOI {
+		t.Errorf("peek at the end got %v", err)
+	}
+}
+
+func TestReplayable(t *testing.T) {
+	testcases := []struct {
+		Comment string
+		Options []iterator.ReplayableOption
+	}{
+		{
+			Comment: "memory",
+		},
+		{
+			Comment: "spill",
+			Options: []iterator.ReplayableOption{
+				iterator.WithSpill("", 2),
+			},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.Comment, func(t *testing.T) {
+			var (
+				data     = []interface{}{"a", "b", 1, 2, "e"}
+				r        = iterator.NewReplayable(iterator.MustNew(data), tt.Options...)
+				replayed = r.Replay()
+			)
+			defer r.Close()
+			// consume partially then replay, replayed one pulls the rest
+			if x, err := r.Next(); err != nil || x != "a" {
+				t.Errorf("next got %v %v", x, err)
+			}
+			for i := 0; i < 2; i++ {
+				ret, err := iterator.ToSlice(replayed)
+				if err != nil {
+					t.Error(err)
+				}
+				if !cmp.Equal(ret, data) {
+					t.Errorf("  actual: %v\nexpected: %v", ret, data)
+				}
+				replayed = r.Replay()
+			}
+			ret, err := iterator.ToSlice(r)
+			if err != nil {
+				t.Error(err)
+			}
+			if !cmp.Equal(ret, data[1:]) {
+				t.Errorf("  actual: %v\nexpected: %v", ret, data[1:])
+			}
+			// cursors are dropped at the end
+			for i := 0; i < 100; i++ {
+				if _, err := iterator.ToSlice(r.Replay()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if n := iterator.CursorsOf(r); n != 0 {
+				t.Errorf("cursors should be dropped at the end: %d", n)
+			}
+		})
+	}
+}
+
+func TestCyclic(t *testing.T) {
+	it, err := iterator.ToCyclic(iterator.MustNew([]int{1, 2, 3}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret := []interface{}{}
+	for i := 0; i < 7; i++ {
+		x, err := it.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ret = append(ret, x)
+	}
+	expected := []interface{}{1, 2, 3, 1, 2, 3, 1}
+	if !cmp.Equal(ret, expected) {
+		t.Errorf("  actual: %v\nexpected: %v", ret, expected)
+	}
+	for i := 0; i < 3000; i++ {
+		if _, err := it.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := iterator.CursorsOf(it); n != 0 {
+		t.Errorf("cursors should not grow by cycles: %d", n)
+	}
+	if _, err := iterator.ToCyclic(iterator.MustNew(nil)); err == nil {
+		t.Error("cyclic from empty iterator should fail")
+	}
+}
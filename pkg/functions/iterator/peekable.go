@@ -0,0 +1,60 @@
+package iterator
+
+import "tools/pkg/collections/stack"
+
+type (
+	// Peekable is an iterator that can look ahead and push back elements
+	Peekable interface {
+		Iterator
+		// Peek returns next element without consuming it
+		Peek() (interface{}, error)
+		// Unread pushes back x.
+		// x will be yielded by next Next before the rest
+		Unread(x interface{})
+	}
+
+	peekable struct {
+		iter Iterator
+		buf  stack.Stack
+		err  error
+	}
+)
+
+// NewPeekable wraps an iterator so that it can peek and unread
+func NewPeekable(iter Iterator) Peekable {
+	if p, ok := iter.(Peekable); ok {
+		return p
+	}
+	return &peekable{
+		iter: iter,
+		buf:  stack.New(),
+	}
+}
+
+func (s *peekable) Next() (interface{}, error) {
+	if x, err := s.buf.Pop(); err == nil {
+		return x, nil
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	x, err := s.iter.Next()
+	if err != nil {
+		s.err = err
+		return nil, err
+	}
+	return x, nil
+}
+
+func (s *peekable) Peek() (interface{}, error) {
+	x, err := s.Next()
+	if err != nil {
+		return nil, err
+	}
+	s.Unread(x)
+	return x, nil
+}
+
+func (s *peekable) Unread(x interface{}) {
+	s.buf.Push(x)
+}
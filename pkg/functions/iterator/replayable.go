@@ -0,0 +1,211 @@
+package iterator
+
+import (
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"sync"
+	"tools/pkg/errors"
+)
+
+type (
+	// Replayable is an iterator that can be iterated more than once.
+	// consumed elements are cached in memory or spilled to disk
+	Replayable interface {
+		Iterator
+		// Replay returns an iterator that yields elements from the beginning.
+		// elements not consumed yet are pulled from the original iterator
+		Replay() Iterator
+		// Close releases spilled file
+		Close() error
+	}
+
+	// ReplayableOption changes option of Replayable
+	ReplayableOption func(*replayCache)
+
+	replayCache struct {
+		mux   sync.Mutex
+		iter  Iterator
+		err   error
+		mem   []interface{}
+		limit int
+		dir   string
+		file  *os.File
+		enc   *gob.Encoder
+		nDisk int
+		// cursors have opened the spilled file, a cursor is dropped when it reaches the end
+		cursors map[*replayCursor]struct{}
+	}
+
+	replayCursor struct {
+		cache *replayCache
+		idx   int
+		file  *os.File
+		dec   *gob.Decoder
+		nDec  int
+	}
+
+	// replayCell is a unit of spilled file
+	replayCell struct {
+		V interface{}
+	}
+)
+
+// WithSpill makes Replayable keep at most limit elements in memory,
+// the rest are encoded by encoding/gob into a temporary file in dir.
+// types of elements except builtin types should be registered by gob.Register.
+// default: all elements are kept in memory
+func WithSpill(dir string, limit int) ReplayableOption {
+	return func(s *replayCache) {
+		s.dir = dir
+		s.limit = limit
+	}
+}
+
+// NewReplayable wraps an iterator so that it can be replayed
+func NewReplayable(iter Iterator, options ...ReplayableOption) Replayable {
+	c := &replayCache{
+		iter:    iter,
+		mem:     []interface{}{},
+		limit:   -1,
+		cursors: map[*replayCursor]struct{}{},
+	}
+	for _, opt := range options {
+		opt(c)
+	}
+	return c.newCursor()
+}
+
+func (s *replayCache) newCursor() *replayCursor {
+	return &replayCursor{
+		cache: s,
+	}
+}
+
+// get returns idx-th element
+func (s *replayCache) get(r *replayCursor, idx int) (interface{}, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if idx < len(s.mem) {
+		return s.mem[idx], nil
+	}
+	if i := idx - len(s.mem); i < s.nDisk {
+		return r.decode(i)
+	}
+	if s.err != nil {
+		r.release()
+		return nil, s.err
+	}
+	x, err := s.iter.Next()
+	if err != nil {
+		s.err = err
+		r.release()
+		return nil, err
+	}
+	if err := s.put(x); err != nil {
+		s.err = err
+		return nil, err
+	}
+	return x, nil
+}
+
+func (s *replayCache) put(x interface{}) error {
+	if s.limit < 0 || len(s.mem) < s.limit {
+		s.mem = append(s.mem, x)
+		return nil
+	}
+	if s.file == nil {
+		f, err := ioutil.TempFile(s.dir, "replayable")
+		if err != nil {
+			return errors.NewError().SetCode(errors.IO).SetError(err)
+		}
+		s.file = f
+		s.enc = gob.NewEncoder(f)
+	}
+	if err := s.enc.Encode(&replayCell{V: x}); err != nil {
+		return errors.NewError().SetCode(errors.IO).SetError(err)
+	}
+	s.nDisk++
+	return nil
+}
+
+func (s *replayCache) close() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for r := range s.cursors {
+		r.release()
+	}
+	if s.file == nil {
+		return nil
+	}
+	name := s.file.Name()
+	_ = s.file.Close()
+	s.file = nil
+	if err := os.Remove(name); err != nil {
+		return errors.NewError().SetCode(errors.IO).SetError(err)
+	}
+	return nil
+}
+
+// decode reads i-th element from spilled file.
+// cursor moves forward only
+func (s *replayCursor) decode(i int) (interface{}, error) {
+	if s.cache.file == nil {
+		return nil, badIterator
+	}
+	if s.file == nil {
+		f, err := os.Open(s.cache.file.Name())
+		if err != nil {
+			return nil, errors.NewError().SetCode(errors.IO).SetError(err)
+		}
+		s.file = f
+		s.dec = gob.NewDecoder(f)
+		s.cache.cursors[s] = struct{}{}
+	}
+	var cell replayCell
+	for s.nDec <= i {
+		cell = replayCell{}
+		if err := s.dec.Decode(&cell); err != nil {
+			return nil, errors.NewError().SetCode(errors.IO).SetError(err)
+		}
+		s.nDec++
+	}
+	return cell.V, nil
+}
+
+func (s *replayCursor) Next() (interface{}, error) {
+	x, err := s.cache.get(s, s.idx)
+	if err != nil {
+		return nil, err
+	}
+	s.idx++
+	return x, nil
+}
+
+// release closes spilled file of the cursor and drops it from the cache.
+// cache must be locked
+func (s *replayCursor) release() {
+	if s.file != nil {
+		_ = s.file.Close()
+		s.file = nil
+		s.dec = nil
+		s.nDec = 0
+	}
+	delete(s.cache.cursors, s)
+}
+
+// rewind moves cursor to the beginning
+func (s *replayCursor) rewind() {
+	s.cache.mux.Lock()
+	defer s.cache.mux.Unlock()
+	s.idx = 0
+	s.release()
+}
+
+func (s *replayCursor) Replay() Iterator {
+	return s.cache.newCursor()
+}
+
+func (s *replayCursor) Close() error {
+	return s.cache.close()
+}
@@ -1,5 +1,7 @@
 package iterator
 
+import "tools/pkg/errors"
+
 // Join merges 2 iterators
 func Join(x, y Iterator) Iterator {
 	var useSecond bool
@@ -8,7 +10,7 @@ func Join(x, y Iterator) Iterator {
 			return y.Next()
 		}
 		elem, err := x.Next()
-		if err == EOI {
+		if errors.Is(err, EOI) {
 			useSecond = true
 			return y.Next()
 		}
@@ -31,35 +33,35 @@ func ToReversed(iter Iterator) (Iterator, error) {
 	return New(r)
 }
 
-type (
-	cyclicIterator struct {
-		idx   int
-		slice []interface{}
-	}
-)
-
 // ToCyclic converts an iterator into an infinite iterator that yields elements cyclicly.
 // Original iterator must be finite and have an element at least.
 func ToCyclic(iter Iterator) (Iterator, error) {
-	slice, err := ToSlice(iter)
-	if err != nil {
+	p := NewPeekable(iter)
+	if _, err := p.Peek(); err != nil {
+		if errors.Is(err, EOI) {
+			return nil, badIterator
+		}
 		return nil, err
 	}
-	if len(slice) == 0 {
-		return nil, badIterator
-	}
 	return &cyclicIterator{
-		slice: slice,
+		r: NewReplayable(p).(*replayCursor),
 	}, nil
 }
 
+type (
+	// cyclicIterator rewinds a cursor on every cycle instead of replaying
+	cyclicIterator struct {
+		r *replayCursor
+	}
+)
+
 func (s *cyclicIterator) Next() (interface{}, error) {
-	if s.idx >= len(s.slice) {
-		s.idx = 0
+	x, err := s.r.Next()
+	if errors.Is(err, EOI) {
+		s.r.rewind()
+		return s.r.Next()
 	}
-	v := s.slice[s.idx]
-	s.idx++
-	return v, nil
+	return x, err
 }
 
 type (
@@ -1,23 +1,44 @@
 package lift
 
 import (
+	"fmt"
 	"reflect"
 	"tools/pkg/conv/reflection"
 	"tools/pkg/errors"
 	"tools/pkg/functions/executor"
 	"tools/pkg/functions/iterator"
+	"tools/pkg/log"
 )
 
 type (
-	// Executor is flat executor
+	// Executor is lift executor
 	Executor struct {
 		hooks executor.Hookable
 		iter  iterator.Iterator
+		t     reflect.Type
+
+		conversion []reflection.Option
 	}
 	// Option changes option of Executor
 	Option func(*Executor)
 )
 
+var (
+	interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+	// sharedInterfaces are candidates of common type, in order of preference
+	sharedInterfaces = []reflect.Type{
+		reflect.TypeOf((*error)(nil)).Elem(),
+		reflect.TypeOf((*fmt.Stringer)(nil)).Elem(),
+	}
+)
+
+// WithType lifts stream into []t instead of inferred type
+func WithType(t reflect.Type) Option {
+	return func(s *Executor) {
+		s.t = t
+	}
+}
+
 // WithHook add hook
 func WithHook(ht executor.HookType, h interface{}) Option {
 	return func(s *Executor) {
@@ -25,6 +46,21 @@ func WithHook(ht executor.HookType, h interface{}) Option {
 	}
 }
 
+// WithLogger logs progress at debug level with field stage=lift
+func WithLogger(l log.Logger) Option {
+	return func(s *Executor) {
+		s.iter = executor.AddLogHooks(s.hooks, l.With("stage", "lift"), s.iter)
+	}
+}
+
+// WithConversion specifies options of conversion of lifted elements and arguments of hooks
+func WithConversion(options ...reflection.Option) Option {
+	return func(s *Executor) {
+		s.conversion = append(s.conversion, options...)
+		s.hooks.SetConversion(s.conversion...)
+	}
+}
+
 func NewExecutor(iter iterator.Iterator, options ...Option) (*Executor, errors.Error) {
 	executor := &Executor{
 		hooks: executor.NewHookable(),
@@ -51,8 +87,11 @@ func (s *Executor) Execute() (iterator.Iterator, error) {
 		})), nil
 	}
 
-	t := getCommonType(slice)
-	newSlice, err := reflection.Convert(slice, reflect.SliceOf(t))
+	t := s.t
+	if t == nil {
+		t = getCommonType(slice)
+	}
+	newSlice, err := reflection.Convert(slice, reflect.SliceOf(t), s.conversion...)
 	if err != nil {
 		return nil, err
 	}
@@ -70,16 +109,155 @@ func (s *Executor) Execute() (iterator.Iterator, error) {
 	})), nil
 }
 
+// getCommonType returns the narrowest type every element can be converted into without loss.
+// same type, widened number, shared interface or interface{} in order of preference,
+// integers are widened into float64 only if they are within ±2^53.
+// nil elements require nilable type
 func getCommonType(v []interface{}) reflect.Type {
-	defaultType := reflect.TypeOf([]interface{}{})
-	if len(v) == 0 {
-		return defaultType
+	var (
+		types   = []reflect.Type{}
+		seen    = map[reflect.Type]bool{}
+		hasNil  bool
+		nilable = func(t reflect.Type) bool {
+			switch t.Kind() {
+			case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+				return true
+			}
+			return false
+		}
+	)
+	for _, x := range v {
+		if x == nil {
+			hasNil = true
+			continue
+		}
+		if t := reflect.TypeOf(x); !seen[t] {
+			seen[t] = true
+			types = append(types, t)
+		}
+	}
+	if len(types) == 0 {
+		return interfaceType
 	}
-	t := reflect.TypeOf(v[0])
+	if len(types) == 1 {
+		if t := types[0]; !hasNil || nilable(t) {
+			return t
+		}
+		return interfaceType
+	}
+	if !hasNil {
+		if t, ok := widenNumber(types); ok && (t.Kind() != reflect.Float64 || fitFloat64(v)) {
+			return t
+		}
+	}
+	for _, it := range sharedInterfaces {
+		if implementsAll(types, it) {
+			return it
+		}
+	}
+	return interfaceType
+}
+
+func implementsAll(types []reflect.Type, it reflect.Type) bool {
+	for _, t := range types {
+		if !t.Implements(it) {
+			return false
+		}
+	}
+	return true
+}
+
+// widenNumber returns the narrowest builtin number type every type can be converted into.
+// floats and integers are widened into float64, it may lose precision of integers over 2^53
+func widenNumber(types []reflect.Type) (reflect.Type, bool) {
+	var (
+		hasFloat          bool
+		intBits, uintBits int
+		float32Only       = true
+		isBuiltin         = func(t reflect.Type) bool { return t.PkgPath() == "" && t.Name() != "" }
+	)
+	for _, t := range types {
+		if !isBuiltin(t) {
+			return nil, false
+		}
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if b := t.Bits(); b > intBits {
+				intBits = b
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if b := t.Bits(); b > uintBits {
+				uintBits = b
+			}
+		case reflect.Float32:
+			hasFloat = true
+		case reflect.Float64:
+			hasFloat = true
+			float32Only = false
+		default:
+			return nil, false
+		}
+	}
+	switch {
+	case hasFloat:
+		if float32Only && intBits == 0 && uintBits == 0 {
+			return reflect.TypeOf(float32(0)), true
+		}
+		return reflect.TypeOf(float64(0)), true
+	case uintBits == 0:
+		return intType(intBits), true
+	case intBits == 0:
+		return uintType(uintBits), true
+	case uintBits < 64:
+		// signed type wider than unsigned ones holds both
+		if intBits <= uintBits {
+			intBits = uintBits * 2
+		}
+		return intType(intBits), true
+	}
+	return nil, false
+}
+
+// maxExactFloat64 is the largest integer that float64 represents exactly along with all smaller ones
+const maxExactFloat64 = 1 << 53
+
+// fitFloat64 returns true if every integer element is converted into float64 without loss
+func fitFloat64(v []interface{}) bool {
 	for _, x := range v {
-		if reflect.TypeOf(x).String() != t.String() {
-			return defaultType
+		switch rv := reflect.ValueOf(x); rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if n := rv.Int(); n > maxExactFloat64 || n < -maxExactFloat64 {
+				return false
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if rv.Uint() > maxExactFloat64 {
+				return false
+			}
 		}
 	}
-	return t
+	return true
+}
+
+func intType(bits int) reflect.Type {
+	switch bits {
+	case 8:
+		return reflect.TypeOf(int8(0))
+	case 16:
+		return reflect.TypeOf(int16(0))
+	case 32:
+		return reflect.TypeOf(int32(0))
+	}
+	return reflect.TypeOf(int64(0))
+}
+
+func uintType(bits int) reflect.Type {
+	switch bits {
+	case 8:
+		return reflect.TypeOf(uint8(0))
+	case 16:
+		return reflect.TypeOf(uint16(0))
+	case 32:
+		return reflect.TypeOf(uint32(0))
+	}
+	return reflect.TypeOf(uint64(0))
 }
@@ -14,7 +14,7 @@ var (
 type (
 	// Mapper :: a -> b
 	Mapper interface {
-		Apply(v interface{}) (interface{}, error)
+		Apply(v interface{}, options ...reflection.Option) (interface{}, error)
 	}
 
 	mapper struct {
@@ -41,10 +41,10 @@ func NewMapper(f interface{}) (Mapper, errors.Error) {
 	}, nil
 }
 
-func (s *mapper) Apply(v interface{}) (interface{}, error) {
-	av, err := reflection.ConvertShallow(v, s.t.In(0))
+func (s *mapper) Apply(v interface{}, options ...reflection.Option) (interface{}, error) {
+	av, err := reflection.ConvertShallow(v, s.t.In(0), options...)
 	if err != nil {
-		return nil, errors.NewError().SetCode(errors.Conversion).SetError(fmt.Errorf("invalid argument for mapper: %v", err))
+		return nil, errors.NewError().SetCode(errors.Conversion).SetError(fmt.Errorf("invalid argument for mapper: %w", err))
 	}
 	r := s.v.Call([]reflect.Value{av})
 	return r[0].Interface(), nil
@@ -0,0 +1,153 @@
+package peek
+
+import (
+	"fmt"
+	"tools/pkg/conv/reflection"
+	"tools/pkg/errors"
+	"tools/pkg/functions/consume"
+	"tools/pkg/functions/executor"
+	"tools/pkg/functions/iterator"
+	"tools/pkg/log"
+)
+
+var (
+	InvalidEvery     = errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("every must be positive"))
+	InvalidMaxLength = errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("max length must not be negative"))
+	InvalidLogger    = errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("logger is nil"))
+)
+
+type (
+	// Executor is peek executor.
+	// yields elements as is
+	Executor struct {
+		hooks      executor.Hookable
+		iter       iterator.Iterator
+		peek       func(x interface{}, index int) error
+		every      int
+		maxLength  int
+		conversion []reflection.Option
+	}
+	// Option changes option of Executor
+	Option func(*Executor)
+)
+
+// WithHook add hook
+func WithHook(ht executor.HookType, h interface{}) Option {
+	return func(s *Executor) {
+		s.hooks.AddHook(ht, h)
+	}
+}
+
+// WithLogger logs progress at debug level with field stage=peek
+func WithLogger(l log.Logger) Option {
+	return func(s *Executor) {
+		s.iter = executor.AddLogHooks(s.hooks, l.With("stage", "peek"), s.iter)
+	}
+}
+
+// WithConversion specifies options of conversion of arguments of functions and hooks
+func WithConversion(options ...reflection.Option) Option {
+	return func(s *Executor) {
+		s.conversion = append(s.conversion, options...)
+		s.hooks.SetConversion(s.conversion...)
+	}
+}
+
+// WithEvery peeks every n-th element, the first one included.
+// default: 1
+func WithEvery(n int) Option {
+	return func(s *Executor) {
+		s.every = n
+	}
+}
+
+// WithMaxLength truncates value of logs by NewDebugExecutor into n characters, 0 is unlimited.
+// default: 0
+func WithMaxLength(n int) Option {
+	return func(s *Executor) {
+		s.maxLength = n
+	}
+}
+
+func newExecutor(iter iterator.Iterator, options []Option) (*Executor, errors.Error) {
+	executor := &Executor{
+		hooks: executor.NewHookable(),
+		iter:  iter,
+		every: 1,
+	}
+	for _, opt := range options {
+		opt(executor)
+	}
+	if executor.every < 1 {
+		return nil, InvalidEvery
+	}
+	if executor.maxLength < 0 {
+		return nil, InvalidMaxLength
+	}
+	return executor, nil
+}
+
+// NewExecutor creates Executor invokes f with elements
+//
+// f :: a
+func NewExecutor(f consume.Consumer, iter iterator.Iterator, options ...Option) (*Executor, errors.Error) {
+	executor, err := newExecutor(iter, options)
+	if err != nil {
+		return nil, err
+	}
+	executor.peek = func(x interface{}, _ int) error {
+		return f.Apply(x, executor.conversion...)
+	}
+	return executor, nil
+}
+
+// NewDebugExecutor creates Executor logs elements at debug level.
+// message is the value, fields are label, 1-based index and type of the element
+func NewDebugExecutor(l log.Logger, label string, iter iterator.Iterator, options ...Option) (*Executor, errors.Error) {
+	if l == nil {
+		return nil, InvalidLogger
+	}
+	executor, err := newExecutor(iter, options)
+	if err != nil {
+		return nil, err
+	}
+	l = l.With("label", label)
+	executor.peek = func(x interface{}, index int) error {
+		l.With("index", index, "type", fmt.Sprintf("%T", x)).Debug("%s", executor.preview(x))
+		return nil
+	}
+	return executor, nil
+}
+
+func (s *Executor) preview(x interface{}) string {
+	r := []rune(fmt.Sprintf("%v", x))
+	if s.maxLength > 0 && len(r) > s.maxLength {
+		return string(r[:s.maxLength]) + "..."
+	}
+	return string(r)
+}
+
+// Execute returns an iterator that yields elements as is after peeking them
+func (s *Executor) Execute() iterator.Iterator {
+	s.hooks.Execute(executor.BeforeHook, s.iter)
+	var index int
+	return iterator.MustNew(iterator.Func(func() (interface{}, error) {
+		x, err := s.iter.Next()
+		if errors.Is(err, iterator.EOI) {
+			s.hooks.Execute(executor.AfterHook)
+			return nil, err
+		}
+		if err != nil {
+			return nil, err
+		}
+		index++
+		s.hooks.Execute(executor.RunningHook, x)
+		if (index-1)%s.every == 0 {
+			if err := s.peek(x, index); err != nil {
+				return nil, errors.NewElementError(err, x)
+			}
+		}
+		s.hooks.Execute(executor.RunningResultHook, x)
+		return x, nil
+	}))
+}
@@ -0,0 +1,146 @@
+package sample
+
+import (
+	"fmt"
+	"time"
+	"tools/pkg/clock"
+	"tools/pkg/conv/reflection"
+	"tools/pkg/errors"
+	"tools/pkg/functions/executor"
+	"tools/pkg/functions/iterator"
+	"tools/pkg/log"
+)
+
+var (
+	InvalidInterval = errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("invalid interval"))
+)
+
+type (
+	// Executor is sample executor
+	Executor struct {
+		hooks   executor.Hookable
+		iter    iterator.Iterator
+		execute func() iterator.Iterator
+		// for NewExecutor
+		clock    clock.Clock
+		interval time.Duration
+		// for random sampling
+		seed        int64
+		k           int
+		p           float64
+		key         *key
+		stratumSize int
+
+		conversion []reflection.Option
+	}
+	// Option changes option of Executor
+	Option func(*Executor)
+)
+
+// WithHook add hook
+func WithHook(ht executor.HookType, h interface{}) Option {
+	return func(s *Executor) {
+		s.hooks.AddHook(ht, h)
+	}
+}
+
+// WithLogger logs progress at debug level with field stage=sample
+func WithLogger(l log.Logger) Option {
+	return func(s *Executor) {
+		s.iter = executor.AddLogHooks(s.hooks, l.With("stage", "sample"), s.iter)
+	}
+}
+
+// WithConversion specifies options of conversion of arguments of functions and hooks
+func WithConversion(options ...reflection.Option) Option {
+	return func(s *Executor) {
+		s.conversion = append(s.conversion, options...)
+		s.hooks.SetConversion(s.conversion...)
+	}
+}
+
+// WithClock specifies clock to decide intervals.
+// default: system clock
+func WithClock(c clock.Clock) Option {
+	return func(s *Executor) {
+		s.clock = c
+	}
+}
+
+// NewExecutor creates Executor yields the latest element per interval
+func NewExecutor(iter iterator.Iterator, interval time.Duration, options ...Option) (*Executor, errors.Error) {
+	if interval <= 0 {
+		return nil, InvalidInterval
+	}
+	executor := newExecutor(iter, options)
+	executor.interval = interval
+	executor.execute = executor.executeInterval
+	return executor, nil
+}
+
+func newExecutor(iter iterator.Iterator, options []Option) *Executor {
+	executor := &Executor{
+		hooks:       executor.NewHookable(),
+		iter:        iter,
+		clock:       clock.New(),
+		stratumSize: 1,
+	}
+	for _, opt := range options {
+		opt(executor)
+	}
+	return executor
+}
+
+// Execute returns an iterator that yields sampled elements
+func (s *Executor) Execute() iterator.Iterator {
+	s.hooks.Execute(executor.BeforeHook, s.iter)
+	return s.execute()
+}
+
+// executeInterval returns an iterator that yields the latest element per interval.
+// intervals start when the first element arrives,
+// an element is yielded when an element of the next interval arrives or the source reaches the end
+func (s *Executor) executeInterval() iterator.Iterator {
+	var (
+		latest    interface{}
+		hasLatest bool
+		windowEnd time.Time
+		isEOI     bool
+	)
+	return iterator.MustNew(iterator.Func(func() (interface{}, error) {
+		for !isEOI {
+			x, err := s.iter.Next()
+			if errors.Is(err, iterator.EOI) {
+				isEOI = true
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			now := s.clock.Now()
+			s.hooks.Execute(executor.RunningHook, x)
+			if !hasLatest {
+				latest, hasLatest = x, true
+				windowEnd = now.Add(s.interval)
+				continue
+			}
+			if now.Before(windowEnd) {
+				latest = x
+				continue
+			}
+			ret := latest
+			latest = x
+			// skip windows elapsed without elements
+			windowEnd = windowEnd.Add((now.Sub(windowEnd)/s.interval + 1) * s.interval)
+			s.hooks.Execute(executor.RunningResultHook, ret)
+			return ret, nil
+		}
+		if hasLatest {
+			hasLatest = false
+			s.hooks.Execute(executor.RunningResultHook, latest)
+			return latest, nil
+		}
+		s.hooks.Execute(executor.AfterHook)
+		return nil, iterator.EOI
+	}))
+}
@@ -0,0 +1,229 @@
+package sample
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"tools/pkg/conv/reflection"
+	"tools/pkg/errors"
+	"tools/pkg/functions/executor"
+	"tools/pkg/functions/iterator"
+)
+
+var (
+	InvalidK           = errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("k must be positive"))
+	InvalidFraction    = errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("fraction must be in [0, 1]"))
+	InvalidKey         = errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("key must be a -> k, k is comparable"))
+	InvalidStratumSize = errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("stratum size must be positive"))
+)
+
+// WithSeed specifies seed of random sampling, the same seed yields the same sample.
+// default: 0
+func WithSeed(seed int64) Option {
+	return func(s *Executor) {
+		s.seed = seed
+	}
+}
+
+// WithStratumSize specifies number of elements sampled per key for stratified sampling.
+// default: 1
+func WithStratumSize(k int) Option {
+	return func(s *Executor) {
+		s.stratumSize = k
+	}
+}
+
+// NewReservoirExecutor creates Executor yields k elements sampled uniformly
+func NewReservoirExecutor(iter iterator.Iterator, k int, options ...Option) (*Executor, errors.Error) {
+	if k < 1 {
+		return nil, InvalidK
+	}
+	executor := newExecutor(iter, options)
+	executor.k = k
+	executor.execute = executor.executeReservoir
+	return executor, nil
+}
+
+// NewFractionExecutor creates Executor yields each element with probability p
+func NewFractionExecutor(iter iterator.Iterator, p float64, options ...Option) (*Executor, errors.Error) {
+	if !(p >= 0 && p <= 1) {
+		return nil, InvalidFraction
+	}
+	executor := newExecutor(iter, options)
+	executor.p = p
+	executor.execute = executor.executeFraction
+	return executor, nil
+}
+
+// NewStratifiedExecutor creates Executor yields elements sampled uniformly per key,
+// see WithStratumSize. key returns a comparable value, or element error is yielded
+//
+// key :: a -> k
+func NewStratifiedExecutor(iter iterator.Iterator, f interface{}, options ...Option) (*Executor, errors.Error) {
+	k, err := newKey(f)
+	if err != nil {
+		return nil, err
+	}
+	executor := newExecutor(iter, options)
+	if executor.stratumSize < 1 {
+		return nil, InvalidStratumSize
+	}
+	executor.key = k
+	executor.execute = executor.executeStratified
+	return executor, nil
+}
+
+type (
+	key struct {
+		v reflect.Value
+		t reflect.Type
+	}
+
+	// reservoir keeps k elements sampled uniformly by algorithm R
+	reservoir struct {
+		k     int
+		n     int
+		elems []*indexed
+	}
+
+	indexed struct {
+		index int
+		v     interface{}
+	}
+)
+
+func newKey(f interface{}) (*key, errors.Error) {
+	t := reflect.TypeOf(f)
+	if t == nil || t.Kind() != reflect.Func || t.NumIn() != 1 || t.NumOut() != 1 || !t.Out(0).Comparable() {
+		return nil, InvalidKey
+	}
+	return &key{
+		v: reflect.ValueOf(f),
+		t: t,
+	}, nil
+}
+
+func (s *key) Apply(x interface{}, options ...reflection.Option) (interface{}, error) {
+	v, err := reflection.ConvertShallow(x, s.t.In(0), options...)
+	if err != nil {
+		return nil, errors.NewError().SetCode(errors.Conversion).SetError(fmt.Errorf("invalid argument for key: %w", err))
+	}
+	return s.v.Call([]reflect.Value{v})[0].Interface(), nil
+}
+
+func (s *reservoir) add(rnd *rand.Rand, x *indexed) {
+	s.n++
+	if len(s.elems) < s.k {
+		s.elems = append(s.elems, x)
+		return
+	}
+	if j := rnd.Intn(s.n); j < s.k {
+		s.elems[j] = x
+	}
+}
+
+// executeReservoir yields sampled elements in the order they arrived
+func (s *Executor) executeReservoir() iterator.Iterator {
+	r := &reservoir{k: s.k}
+	return s.executeSampled(func(rnd *rand.Rand, x *indexed) error {
+		r.add(rnd, x)
+		return nil
+	}, func() []*indexed {
+		return r.elems
+	})
+}
+
+// executeStratified yields sampled elements in the order they arrived
+func (s *Executor) executeStratified() iterator.Iterator {
+	var (
+		strata = map[interface{}]*reservoir{}
+		keys   = []interface{}{}
+	)
+	return s.executeSampled(func(rnd *rand.Rand, x *indexed) error {
+		k, err := s.key.Apply(x.v, s.conversion...)
+		if err != nil {
+			return errors.NewElementError(err, x.v)
+		}
+		if k != nil && !reflect.TypeOf(k).Comparable() {
+			return errors.NewElementError(errors.NewError().SetCode(errors.Sample).SetError(fmt.Errorf("key is not comparable: %v", k)), x.v)
+		}
+		r, ok := strata[k]
+		if !ok {
+			r = &reservoir{k: s.stratumSize}
+			strata[k] = r
+			keys = append(keys, k)
+		}
+		r.add(rnd, x)
+		return nil
+	}, func() []*indexed {
+		elems := []*indexed{}
+		for _, k := range keys {
+			elems = append(elems, strata[k].elems...)
+		}
+		return elems
+	})
+}
+
+// executeSampled consumes the source by add at the first pull and yields elements by sampled
+func (s *Executor) executeSampled(add func(*rand.Rand, *indexed) error, sampled func() []*indexed) iterator.Iterator {
+	var (
+		rnd   = rand.New(rand.NewSource(s.seed))
+		elems []*indexed
+		done  bool
+		// index of the next element, kept over errors of elements
+		index int
+	)
+	return iterator.MustNew(iterator.Func(func() (interface{}, error) {
+		if !done {
+			for {
+				x, err := s.iter.Next()
+				if errors.Is(err, iterator.EOI) {
+					break
+				}
+				if err != nil {
+					return nil, err
+				}
+				s.hooks.Execute(executor.RunningHook, x)
+				e := &indexed{index: index, v: x}
+				index++
+				if err := add(rnd, e); err != nil {
+					return nil, err
+				}
+			}
+			done = true
+			elems = sampled()
+			sort.Slice(elems, func(i, j int) bool { return elems[i].index < elems[j].index })
+		}
+		if len(elems) == 0 {
+			s.hooks.Execute(executor.AfterHook)
+			return nil, iterator.EOI
+		}
+		x := elems[0].v
+		elems = elems[1:]
+		s.hooks.Execute(executor.RunningResultHook, x)
+		return x, nil
+	}))
+}
+
+// executeFraction yields each element with probability p
+func (s *Executor) executeFraction() iterator.Iterator {
+	rnd := rand.New(rand.NewSource(s.seed))
+	return iterator.MustNew(iterator.Func(func() (interface{}, error) {
+		for {
+			x, err := s.iter.Next()
+			if errors.Is(err, iterator.EOI) {
+				s.hooks.Execute(executor.AfterHook)
+				return nil, iterator.EOI
+			}
+			if err != nil {
+				return nil, err
+			}
+			s.hooks.Execute(executor.RunningHook, x)
+			if rnd.Float64() < s.p {
+				s.hooks.Execute(executor.RunningResultHook, x)
+				return x, nil
+			}
+		}
+	}))
+}
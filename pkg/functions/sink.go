@@ -0,0 +1,459 @@
+package functions
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+	"tools/pkg/errors"
+	"tools/pkg/io/compress"
+)
+
+type (
+	// Encoder writes elements into writer.
+	// output is buffered until Flush
+	Encoder interface {
+		Encode(x interface{}) error
+		Flush() error
+	}
+
+	lineEncoder struct {
+		w *bufio.Writer
+	}
+)
+
+// NewLineEncoder creates an encoder writes []byte or string element followed by a newline
+func NewLineEncoder(w io.Writer) Encoder {
+	return &lineEncoder{
+		w: bufio.NewWriter(w),
+	}
+}
+
+func (s *lineEncoder) Encode(x interface{}) error {
+	var err error
+	switch x := x.(type) {
+	case []byte:
+		_, err = s.w.Write(x)
+	case string:
+		_, err = s.w.WriteString(x)
+	default:
+		_, err = fmt.Fprint(s.w, x)
+	}
+	if err != nil {
+		return err
+	}
+	return s.w.WriteByte('\n')
+}
+
+func (s *lineEncoder) Flush() error { return s.w.Flush() }
+
+type (
+	jsonLinesEncoder struct {
+		w   *bufio.Writer
+		enc *json.Encoder
+	}
+)
+
+// NewJSONLinesEncoder creates an encoder writes an element as a line of JSON
+func NewJSONLinesEncoder(w io.Writer) Encoder {
+	bw := bufio.NewWriter(w)
+	return &jsonLinesEncoder{
+		w:   bw,
+		enc: json.NewEncoder(bw),
+	}
+}
+
+func (s *jsonLinesEncoder) Encode(x interface{}) error { return s.enc.Encode(x) }
+func (s *jsonLinesEncoder) Flush() error               { return s.w.Flush() }
+
+type (
+	flushingEncoder struct {
+		Encoder
+	}
+)
+
+// NewFlushingEncoder creates an encoder flushes enc at every element,
+// so that long-running pipelines show progress at the cost of a write per element
+func NewFlushingEncoder(enc Encoder) Encoder {
+	return &flushingEncoder{Encoder: enc}
+}
+
+func (s *flushingEncoder) Encode(x interface{}) error {
+	if err := s.Encoder.Encode(x); err != nil {
+		return err
+	}
+	return s.Encoder.Flush()
+}
+
+type (
+	csvEncoder struct {
+		w       *csv.Writer
+		columns []string
+		// derived is true if columns are derived from the first element
+		derived bool
+		started bool
+		// fields are cached for the struct type of the last element
+		fieldsType reflect.Type
+		fields     []*taggedField
+	}
+)
+
+// NewCSVEncoder creates an encoder writes an element as a row of CSV.
+// element is a struct, a pointer to struct or a map.
+// header is derived from the first element unless columns are specified,
+// fields of struct are named by `csv` tag or field name, keys of map are sorted.
+// map element with a key out of derived header is an error, keys out of specified columns are ignored
+func NewCSVEncoder(w io.Writer, columns ...string) Encoder {
+	return newCSVEncoder(w, ',', columns)
+}
+
+// NewTSVEncoder creates an encoder writes an element as a row of TSV.
+// same as NewCSVEncoder but fields are separated by tab
+func NewTSVEncoder(w io.Writer, columns ...string) Encoder {
+	return newCSVEncoder(w, '\t', columns)
+}
+
+func newCSVEncoder(w io.Writer, comma rune, columns []string) Encoder {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	return &csvEncoder{
+		w:       cw,
+		columns: columns,
+	}
+}
+
+func (s *csvEncoder) Encode(x interface{}) error {
+	v := reflect.ValueOf(x)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if !s.started {
+		s.started = true
+		if len(s.columns) == 0 {
+			s.columns = csvColumnsOf(v)
+			s.derived = true
+		}
+		if err := s.w.Write(s.columns); err != nil {
+			return err
+		}
+	}
+	row := make([]string, len(s.columns))
+	switch v.Kind() {
+	case reflect.Struct:
+		if s.fieldsType != v.Type() {
+			s.fieldsType = v.Type()
+			s.fields = newCSVFields(v.Type(), s.columns)
+		}
+		for i, f := range s.fields {
+			if f != nil {
+				row[i] = csvFieldString(v.FieldByIndex(f.index))
+			}
+		}
+	case reflect.Map:
+		if s.derived && v.Len() > 0 {
+			if err := s.validateKeys(v); err != nil {
+				return err
+			}
+		}
+		for i, c := range s.columns {
+			if x := v.MapIndex(reflect.ValueOf(c)); x.IsValid() {
+				row[i] = csvFieldString(x)
+			}
+		}
+	default:
+		return fmt.Errorf("cannot encode %v as CSV", v.Type())
+	}
+	return s.w.Write(row)
+}
+
+// validateKeys returns error if map has a key out of columns
+func (s *csvEncoder) validateKeys(v reflect.Value) error {
+	for _, k := range v.MapKeys() {
+		name := fmt.Sprint(k.Interface())
+		var found bool
+		for _, c := range s.columns {
+			if c == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown column %s of %v", name, v.Interface())
+		}
+	}
+	return nil
+}
+
+func (s *csvEncoder) Flush() error {
+	s.w.Flush()
+	return s.w.Error()
+}
+
+// csvColumnsOf returns header from struct or map
+func csvColumnsOf(v reflect.Value) []string {
+	return taggedColumnsOf(v, "csv")
+}
+
+// taggedColumnsOf returns names of fields by tag or field name from struct, or sorted keys from map
+func taggedColumnsOf(v reflect.Value, tagName string) []string {
+	columns := []string{}
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			name := f.Name
+			if tag, ok := f.Tag.Lookup(tagName); ok {
+				if tag == "-" {
+					continue
+				}
+				name = tag
+			}
+			columns = append(columns, name)
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			columns = append(columns, fmt.Sprint(k.Interface()))
+		}
+		sort.Strings(columns)
+	}
+	return columns
+}
+
+func csvFieldString(v reflect.Value) string {
+	x := v.Interface()
+	switch x := x.(type) {
+	case string:
+		return x
+	case []byte:
+		return string(x)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(x)
+}
+
+// Sink consumes a stream.
+// encode every element yielded from the stream and flush at the end.
+// invoke onError on encoder error, return the error of the stream, e.g. skipped errors
+func Sink(enc Encoder, onError func(error), st Stream) error {
+	if err := st.Consume(func(x interface{}) {
+		if err := enc.Encode(x); err != nil && onError != nil {
+			onError(err)
+		}
+	}); err != nil {
+		_ = enc.Flush()
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return errors.NewError().SetCode(errors.IO).SetError(err)
+	}
+	return st.Err()
+}
+
+// SinkJSONLinesToWriter consumes a stream.
+// write to writer an element as a line of JSON.
+// invoke onError on writer error
+func SinkJSONLinesToWriter(w io.Writer, onError func(error), st Stream) error {
+	return Sink(NewJSONLinesEncoder(w), onError, st)
+}
+
+// SinkCSVToWriter consumes a stream.
+// write to writer an element as a row of CSV, see NewCSVEncoder.
+// invoke onError on writer error
+func SinkCSVToWriter(w io.Writer, onError func(error), st Stream, columns ...string) error {
+	return Sink(NewCSVEncoder(w, columns...), onError, st)
+}
+
+// SinkCompressedToWriter calls sink with a writer that compresses into w as format f,
+// e.g. `SinkCompressedToWriter(os.Stdout, compress.Gzip, func(w io.Writer) error { return SinkCSVToWriter(w, nil, st) })`.
+// compressed data is flushed after sink returns, w is not closed
+func SinkCompressedToWriter(w io.Writer, f compress.Format, sink func(io.Writer) error) error {
+	cw, err := compress.NewWriter(w, f)
+	if err != nil {
+		return err
+	}
+	sErr := sink(cw)
+	if err := cw.Close(); err != nil && sErr == nil {
+		return errors.NewError().SetCode(errors.IO).SetError(err)
+	}
+	return sErr
+}
+
+type (
+	// RotateOption changes option of SinkToRotatingFiles
+	RotateOption func(*rotatingSink)
+
+	rotatingSink struct {
+		name        *template.Template
+		newEncoder  func(io.Writer) Encoder
+		maxBytes    int64
+		maxElements int
+		compression compress.Format
+		index       int
+		file        *os.File
+		buf         *bufio.Writer
+		compressor  io.WriteCloser
+		counter     *countingWriter
+		enc         Encoder
+		elements    int
+	}
+
+	// RotateFileName is data for the template of file name
+	RotateFileName struct {
+		// Index is 0-based sequence number of file
+		Index int
+		// Time is when file is opened
+		Time time.Time
+	}
+
+	countingWriter struct {
+		w io.Writer
+		n int64
+	}
+)
+
+func (s *countingWriter) Write(p []byte) (int, error) {
+	n, err := s.w.Write(p)
+	s.n += int64(n)
+	return n, err
+}
+
+// WithMaxBytes rotates file when written bytes reach n
+func WithMaxBytes(n int64) RotateOption {
+	return func(s *rotatingSink) {
+		s.maxBytes = n
+	}
+}
+
+// WithCompression compresses each file as format f.
+// bytes for WithMaxBytes are counted before compression
+func WithCompression(f compress.Format) RotateOption {
+	return func(s *rotatingSink) {
+		s.compression = f
+	}
+}
+
+// WithMaxElements rotates file when written elements reach n
+func WithMaxElements(n int) RotateOption {
+	return func(s *rotatingSink) {
+		s.maxElements = n
+	}
+}
+
+// SinkToRotatingFiles consumes a stream.
+// write elements by encoders from newEncoder into files.
+// file names are from nameTemplate, text/template with RotateFileName, e.g. `out-{{printf "%03d" .Index}}.jsonl`.
+// invoke onError on writer error
+func SinkToRotatingFiles(nameTemplate string, newEncoder func(io.Writer) Encoder, onError func(error), st Stream, options ...RotateOption) error {
+	tmpl, err := template.New("name").Parse(nameTemplate)
+	if err != nil {
+		return errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("invalid template: %w", err))
+	}
+	s := &rotatingSink{
+		name:        tmpl,
+		newEncoder:  newEncoder,
+		compression: compress.None,
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+	var fErr = func(err error) {
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	}
+	cErr := st.Consume(func(x interface{}) {
+		if s.shouldRotate() {
+			if err := s.close(); err != nil {
+				fErr(err)
+				return
+			}
+		}
+		if s.file == nil {
+			if err := s.open(); err != nil {
+				fErr(err)
+				return
+			}
+		}
+		fErr(s.enc.Encode(x))
+		// encoder buffer is drained into file buffer to count bytes
+		fErr(s.enc.Flush())
+		s.elements++
+	})
+	if err := s.close(); err != nil {
+		return errors.NewError().SetCode(errors.IO).SetError(err)
+	}
+	if cErr != nil {
+		return cErr
+	}
+	return st.Err()
+}
+
+func (s *rotatingSink) shouldRotate() bool {
+	if s.file == nil {
+		return false
+	}
+	return (s.maxElements > 0 && s.elements >= s.maxElements) ||
+		(s.maxBytes > 0 && s.counter.n >= s.maxBytes)
+}
+
+func (s *rotatingSink) open() error {
+	var b strings.Builder
+	if err := s.name.Execute(&b, &RotateFileName{
+		Index: s.index,
+		Time:  time.Now(),
+	}); err != nil {
+		return err
+	}
+	f, err := os.Create(b.String())
+	if err != nil {
+		return err
+	}
+	buf := bufio.NewWriter(f)
+	cw, err := compress.NewWriter(buf, s.compression)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	s.index++
+	s.file = f
+	s.buf = buf
+	s.compressor = cw
+	s.counter = &countingWriter{w: cw}
+	s.enc = s.newEncoder(s.counter)
+	s.elements = 0
+	return nil
+}
+
+func (s *rotatingSink) close() error {
+	if s.file == nil {
+		return nil
+	}
+	defer func() {
+		s.file = nil
+	}()
+	if err := s.enc.Flush(); err != nil {
+		_ = s.file.Close()
+		return err
+	}
+	if err := s.compressor.Close(); err != nil {
+		_ = s.file.Close()
+		return err
+	}
+	if err := s.buf.Flush(); err != nil {
+		_ = s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
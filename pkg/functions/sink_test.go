@@ -0,0 +1,256 @@
+package functions_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"tools/pkg/functions"
+	"tools/pkg/functions/iterator"
+	"tools/pkg/io/compress"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type (
+	sinkTestcase struct {
+		Comment string
+		Data    interface{}
+		Sink    func(io.Writer, functions.Stream) error
+		Result  string
+	}
+)
+
+func (s *sinkTestcase) Test(t *testing.T) {
+	var (
+		buf = &bytes.Buffer{}
+		st  = functions.NewStream(iterator.MustNew(s.Data))
+	)
+	if err := s.Sink(buf, st); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != s.Result {
+		t.Errorf("  actual: %q\nexpected: %q", buf.String(), s.Result)
+	}
+}
+
+// writeCounter counts calls of Write
+type writeCounter struct {
+	w io.Writer
+	n int
+}
+
+func (s *writeCounter) Write(p []byte) (int, error) {
+	s.n++
+	return s.w.Write(p)
+}
+
+func TestSink(t *testing.T) {
+	type Row struct {
+		Name   string `csv:"name" json:"name"`
+		Region string `csv:"region" json:"region"`
+		Skip   string `csv:"-" json:"-"`
+	}
+
+	testcases := []*sinkTestcase{
+		{
+			Comment: "line",
+			Data:    []interface{}{[]byte("a"), "b"},
+			Sink: func(w io.Writer, st functions.Stream) error {
+				return functions.SinkLineToWriter(w, nil, st)
+			},
+			Result: "a\nb\n",
+		},
+		{
+			Comment: "line-buffered",
+			Data:    []string{"a", "b", "c"},
+			Sink: func(w io.Writer, st functions.Stream) error {
+				wc := &writeCounter{w: w}
+				if err := functions.SinkLineToWriter(wc, nil, st); err != nil {
+					return err
+				}
+				if wc.n != 1 {
+					return fmt.Errorf("should flush at the end: %d writes", wc.n)
+				}
+				return nil
+			},
+			Result: "a\nb\nc\n",
+		},
+		{
+			Comment: "line-flush",
+			Data:    []string{"a", "b", "c"},
+			Sink: func(w io.Writer, st functions.Stream) error {
+				wc := &writeCounter{w: w}
+				if err := functions.Sink(functions.NewFlushingEncoder(functions.NewLineEncoder(wc)), nil, st); err != nil {
+					return err
+				}
+				if wc.n != 3 {
+					return fmt.Errorf("should flush every element: %d writes", wc.n)
+				}
+				return nil
+			},
+			Result: "a\nb\nc\n",
+		},
+		{
+			Comment: "jsonl",
+			Data: []Row{
+				{Name: "Stela", Region: "Romania"},
+				{Name: "Aud", Region: "Norway"},
+			},
+			Sink: func(w io.Writer, st functions.Stream) error {
+				return functions.SinkJSONLinesToWriter(w, nil, st)
+			},
+			Result: `{"name":"Stela","region":"Romania"}
+{"name":"Aud","region":"Norway"}
+`,
+		},
+		{
+			Comment: "csv-struct",
+			Data: []*Row{
+				{Name: "Stela", Region: "Romania", Skip: "x"},
+				{Name: "Aud, R", Region: "Norway"},
+			},
+			Sink: func(w io.Writer, st functions.Stream) error {
+				return functions.SinkCSVToWriter(w, nil, st)
+			},
+			Result: "name,region\nStela,Romania\n\"Aud, R\",Norway\n",
+		},
+		{
+			Comment: "csv-map",
+			Data: []map[string]interface{}{
+				{"name": "Stela", "region": "Romania", "n": 1},
+				{"name": "Aud", "region": "Norway"},
+			},
+			Sink: func(w io.Writer, st functions.Stream) error {
+				return functions.SinkCSVToWriter(w, nil, st)
+			},
+			Result: "n,name,region\n1,Stela,Romania\n,Aud,Norway\n",
+		},
+		{
+			Comment: "csv-map-unknown-key",
+			Data: []map[string]interface{}{
+				{"name": "Stela"},
+				{"name": "Aud", "region": "Norway"},
+				{"name": "Alex"},
+			},
+			Sink: func(w io.Writer, st functions.Stream) error {
+				var errs []error
+				if err := functions.SinkCSVToWriter(w, func(err error) {
+					errs = append(errs, err)
+				}, st); err != nil {
+					return err
+				}
+				if len(errs) != 1 {
+					return fmt.Errorf("not expected errors: %v", errs)
+				}
+				return nil
+			},
+			Result: "name\nStela\nAlex\n",
+		},
+		{
+			Comment: "csv-columns",
+			Data: []map[string]interface{}{
+				{"name": "Stela", "region": "Romania", "n": 1},
+			},
+			Sink: func(w io.Writer, st functions.Stream) error {
+				return functions.SinkCSVToWriter(w, nil, st, "region", "name")
+			},
+			Result: "region,name\nRomania,Stela\n",
+		},
+		{
+			Comment: "compressed",
+			Data:    []string{"a", "b"},
+			Sink: func(w io.Writer, st functions.Stream) error {
+				var buf bytes.Buffer
+				if err := functions.SinkCompressedToWriter(&buf, compress.Gzip, func(w io.Writer) error {
+					return functions.SinkLineToWriter(w, nil, st)
+				}); err != nil {
+					return err
+				}
+				if f := compress.Detect(buf.Bytes()); f != compress.Gzip {
+					return fmt.Errorf("detected %v", f)
+				}
+				r, err := compress.NewReader(&buf)
+				if err != nil {
+					return err
+				}
+				if _, err := io.Copy(w, r); err != nil {
+					return err
+				}
+				return nil
+			},
+			Result: "a\nb\n",
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.Comment, func(t *testing.T) {
+			tt.Test(t)
+		})
+	}
+}
+
+func TestSinkToRotatingFiles(t *testing.T) {
+	testcases := []struct {
+		Comment string
+		Data    []int
+		Options []functions.RotateOption
+		Result  []string
+	}{
+		{
+			Comment: "no-rotation",
+			Data:    []int{1, 2, 3},
+			Result:  []string{"1\n2\n3\n"},
+		},
+		{
+			Comment: "by-elements",
+			Data:    []int{1, 2, 3, 4, 5},
+			Options: []functions.RotateOption{
+				functions.WithMaxElements(2),
+			},
+			Result: []string{"1\n2\n", "3\n4\n", "5\n"},
+		},
+		{
+			Comment: "by-bytes",
+			Data:    []int{1, 22, 333, 4},
+			Options: []functions.RotateOption{
+				functions.WithMaxBytes(5),
+			},
+			Result: []string{"1\n22\n", "333\n4\n"},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.Comment, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "rotate")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			st := functions.NewStream(iterator.MustNew(tt.Data))
+			tmpl := filepath.Join(dir, `out-{{printf "%02d" .Index}}.txt`)
+			if err := functions.SinkToRotatingFiles(tmpl, functions.NewLineEncoder, func(err error) {
+				t.Error(err)
+			}, st, tt.Options...); err != nil {
+				t.Fatal(err)
+			}
+			result := []string{}
+			for i := 0; ; i++ {
+				b, err := ioutil.ReadFile(filepath.Join(dir, fmt.Sprintf("out-%02d.txt", i)))
+				if os.IsNotExist(err) {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				result = append(result, string(b))
+			}
+			if !cmp.Equal(result, tt.Result) {
+				t.Errorf("  actual: %q\nexpected: %q", result, tt.Result)
+			}
+		})
+	}
+}
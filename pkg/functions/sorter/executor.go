@@ -1,10 +1,14 @@
 package sorter
 
 import (
+	"fmt"
 	"sort"
+	"tools/pkg/collections/heap"
+	"tools/pkg/conv/reflection"
 	"tools/pkg/errors"
 	"tools/pkg/functions/executor"
 	"tools/pkg/functions/iterator"
+	"tools/pkg/log"
 )
 
 type (
@@ -12,7 +16,11 @@ type (
 	Executor struct {
 		hooks executor.Hookable
 		f     Sorter
+		keys  []Key
+		k     int
 		iter  iterator.Iterator
+
+		conversion []reflection.Option
 	}
 	// Option changes option of Executor
 	Option func(*Executor)
@@ -25,6 +33,21 @@ func WithHook(ht executor.HookType, h interface{}) Option {
 	}
 }
 
+// WithLogger logs progress at debug level with field stage=sort
+func WithLogger(l log.Logger) Option {
+	return func(s *Executor) {
+		s.iter = executor.AddLogHooks(s.hooks, l.With("stage", "sort"), s.iter)
+	}
+}
+
+// WithConversion specifies options of conversion of arguments of functions and hooks
+func WithConversion(options ...reflection.Option) Option {
+	return func(s *Executor) {
+		s.conversion = append(s.conversion, options...)
+		s.hooks.SetConversion(s.conversion...)
+	}
+}
+
 func NewExecutor(f Sorter, iter iterator.Iterator, options ...Option) (*Executor, errors.Error) {
 	executor := &Executor{
 		hooks: executor.NewHookable(),
@@ -37,7 +60,62 @@ func NewExecutor(f Sorter, iter iterator.Iterator, options ...Option) (*Executor
 	return executor, nil
 }
 
+var (
+	InvalidK = errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("k must be positive"))
+)
+
+// NewTopKExecutor creates an executor yields k greatest elements by less in descending order.
+// keeps only k elements in memory.
+// equal elements are yielded in the order they arrived, the earlier ones are kept
+func NewTopKExecutor(f Sorter, k int, iter iterator.Iterator, options ...Option) (*Executor, errors.Error) {
+	if k < 1 {
+		return nil, InvalidK
+	}
+	executor, err := NewExecutor(f, iter, options...)
+	if err != nil {
+		return nil, err
+	}
+	executor.k = k
+	return executor, nil
+}
+
+// NewBottomKExecutor creates an executor yields k least elements by less in ascending order.
+// same as NewTopKExecutor but reversed order
+func NewBottomKExecutor(f Sorter, k int, iter iterator.Iterator, options ...Option) (*Executor, errors.Error) {
+	return NewTopKExecutor(&reversed{f: f}, k, iter, options...)
+}
+
+type (
+	reversed struct {
+		f Sorter
+	}
+)
+
+func (s *reversed) Apply(x, y interface{}, options ...reflection.Option) (bool, error) {
+	return s.f.Apply(y, x, options...)
+}
+
+// NewKeyExecutor creates an executor sorts by keys, former keys take precedence.
+// keys are computed once per element
+func NewKeyExecutor(keys []Key, iter iterator.Iterator, options ...Option) (*Executor, errors.Error) {
+	executor := &Executor{
+		hooks: executor.NewHookable(),
+		keys:  keys,
+		iter:  iter,
+	}
+	for _, opt := range options {
+		opt(executor)
+	}
+	return executor, nil
+}
+
 func (s *Executor) Execute() (iterator.Iterator, error) {
+	if s.keys != nil {
+		return s.executeKeys()
+	}
+	if s.k > 0 {
+		return s.executeTopK()
+	}
 	s.hooks.Execute(executor.BeforeHook, s.iter)
 	slice, err := iterator.ToSlice(s.iter)
 	if err != nil {
@@ -46,7 +124,7 @@ func (s *Executor) Execute() (iterator.Iterator, error) {
 	var sError error
 	sort.SliceStable(slice, func(i, j int) bool {
 		s.hooks.Execute(executor.RunningHook, slice[i], slice[j])
-		ret, err := s.f.Apply(slice[i], slice[j])
+		ret, err := s.f.Apply(slice[i], slice[j], s.conversion...)
 		if err != nil && sError == nil {
 			sError = err
 		}
@@ -61,3 +139,126 @@ func (s *Executor) Execute() (iterator.Iterator, error) {
 	defer s.hooks.Execute(executor.AfterHook)
 	return iterator.MustNew(slice), nil
 }
+
+type (
+	keyed struct {
+		x    interface{}
+		keys []interface{}
+	}
+)
+
+// executeKeys sorts stably by keys
+func (s *Executor) executeKeys() (iterator.Iterator, error) {
+	s.hooks.Execute(executor.BeforeHook, s.iter)
+	slice, err := iterator.ToSlice(s.iter)
+	if err != nil {
+		return nil, err
+	}
+	elems := make([]*keyed, len(slice))
+	for i, x := range slice {
+		e := &keyed{
+			x:    x,
+			keys: make([]interface{}, len(s.keys)),
+		}
+		for j, k := range s.keys {
+			v, err := k.Apply(x, s.conversion...)
+			if err != nil {
+				return nil, err
+			}
+			e.keys[j] = v
+		}
+		elems[i] = e
+	}
+	var sError error
+	sort.SliceStable(elems, func(i, j int) bool {
+		s.hooks.Execute(executor.RunningHook, elems[i].x, elems[j].x)
+		var ret bool
+		for k, key := range s.keys {
+			c, err := Compare(elems[i].keys[k], elems[j].keys[k])
+			if err != nil && sError == nil {
+				sError = err
+			}
+			if c == 0 {
+				continue
+			}
+			ret = (c < 0) != key.Desc()
+			break
+		}
+		s.hooks.Execute(executor.RunningResultHook, ret)
+		return ret
+	})
+	if sError != nil {
+		return nil, sError
+	}
+	defer s.hooks.Execute(executor.AfterHook)
+	r := make([]interface{}, len(elems))
+	for i, e := range elems {
+		r[i] = e.x
+	}
+	return iterator.MustNew(r), nil
+}
+
+type (
+	ranked struct {
+		x   interface{}
+		seq int
+	}
+)
+
+// executeTopK selects k greatest elements by min heap of size k
+func (s *Executor) executeTopK() (iterator.Iterator, error) {
+	s.hooks.Execute(executor.BeforeHook, s.iter)
+	var (
+		sError error
+		less   = func(x, y interface{}) bool {
+			s.hooks.Execute(executor.RunningHook, x, y)
+			ret, err := s.f.Apply(x, y, s.conversion...)
+			if err != nil && sError == nil {
+				sError = err
+			}
+			if err == nil {
+				s.hooks.Execute(executor.RunningResultHook, ret)
+			}
+			return ret
+		}
+		// root is the least, the latest among equal ones
+		h = heap.New(func(x, y interface{}) bool {
+			rx, ry := x.(*ranked), y.(*ranked)
+			if less(rx.x, ry.x) {
+				return true
+			}
+			if less(ry.x, rx.x) {
+				return false
+			}
+			return rx.seq > ry.seq
+		})
+	)
+	for seq := 0; ; seq++ {
+		x, err := s.iter.Next()
+		if errors.Is(err, iterator.EOI) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if h.Len() < s.k {
+			h.Push(&ranked{x: x, seq: seq})
+		} else if root, _ := h.Peep(); less(root.(*ranked).x, x) {
+			_, _ = h.Pop()
+			h.Push(&ranked{x: x, seq: seq})
+		}
+		if sError != nil {
+			return nil, sError
+		}
+	}
+	r := make([]interface{}, h.Len())
+	for i := len(r) - 1; i >= 0; i-- {
+		x, _ := h.Pop()
+		r[i] = x.(*ranked).x
+	}
+	if sError != nil {
+		return nil, sError
+	}
+	defer s.hooks.Execute(executor.AfterHook)
+	return iterator.MustNew(r), nil
+}
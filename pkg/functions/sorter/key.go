@@ -0,0 +1,115 @@
+package sorter
+
+import (
+	"fmt"
+	"reflect"
+	"tools/pkg/conv/reflection"
+	"tools/pkg/errors"
+)
+
+var (
+	InvalidKey = errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("invalid key"))
+)
+
+type (
+	// Key :: a -> k, k is compared by natural ordering
+	Key interface {
+		Apply(x interface{}, options ...reflection.Option) (interface{}, error)
+		// Desc is true if descending order
+		Desc() bool
+	}
+
+	key struct {
+		v    reflect.Value
+		t    reflect.Type
+		desc bool
+	}
+
+	// Order is a key function with direction
+	Order struct {
+		f    interface{}
+		desc bool
+	}
+)
+
+// Asc sorts by key function f in ascending order
+func Asc(f interface{}) *Order {
+	return &Order{f: f}
+}
+
+// Desc sorts by key function f in descending order
+func Desc(f interface{}) *Order {
+	return &Order{f: f, desc: true}
+}
+
+func IsKey(f interface{}) bool {
+	t := reflect.TypeOf(f)
+	return t != nil && t.Kind() == reflect.Func &&
+		t.NumIn() == 1 && t.NumOut() == 1
+}
+
+// NewKeys creates keys from key functions a -> k or *Order
+func NewKeys(keys ...interface{}) ([]Key, errors.Error) {
+	if len(keys) == 0 {
+		return nil, InvalidKey
+	}
+	r := make([]Key, len(keys))
+	for i, k := range keys {
+		o, ok := k.(*Order)
+		if !ok {
+			o = Asc(k)
+		}
+		if !IsKey(o.f) {
+			return nil, InvalidKey
+		}
+		r[i] = &key{
+			v:    reflect.ValueOf(o.f),
+			t:    reflect.TypeOf(o.f),
+			desc: o.desc,
+		}
+	}
+	return r, nil
+}
+
+func (s *key) Desc() bool { return s.desc }
+
+func (s *key) Apply(x interface{}, options ...reflection.Option) (interface{}, error) {
+	v, err := reflection.ConvertShallow(x, s.t.In(0), options...)
+	if err != nil {
+		return nil, errors.NewError().SetCode(errors.Conversion).SetError(fmt.Errorf("invalid argument for key: %w", err))
+	}
+	return s.v.Call([]reflect.Value{v})[0].Interface(), nil
+}
+
+// Compare compares x and y by natural ordering of builtin kinds,
+// returns negative if x < y, 0 if x == y, positive if x > y.
+// bool is ordered false then true
+func Compare(x, y interface{}) (int, error) {
+	vx, vy := reflect.ValueOf(x), reflect.ValueOf(y)
+	if !vx.IsValid() || !vy.IsValid() || vx.Kind() != vy.Kind() {
+		return 0, errors.NewError().SetCode(errors.Sort).SetError(fmt.Errorf("cannot compare %T and %T", x, y))
+	}
+	switch vx.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareOrdered(vx.Int() < vy.Int(), vx.Int() > vy.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return compareOrdered(vx.Uint() < vy.Uint(), vx.Uint() > vy.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return compareOrdered(vx.Float() < vy.Float(), vx.Float() > vy.Float()), nil
+	case reflect.String:
+		return compareOrdered(vx.String() < vy.String(), vx.String() > vy.String()), nil
+	case reflect.Bool:
+		return compareOrdered(!vx.Bool() && vy.Bool(), vx.Bool() && !vy.Bool()), nil
+	}
+	return 0, errors.NewError().SetCode(errors.Sort).SetError(fmt.Errorf("cannot compare %T", x))
+}
+
+func compareOrdered(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
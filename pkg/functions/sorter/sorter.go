@@ -12,15 +12,16 @@ var (
 )
 
 type (
-	// Sorter :: a -> a -> bool
+	// Sorter :: a -> a -> bool, or a -> a -> int as three-way comparator
 	Sorter interface {
-		Apply(x, y interface{}) (bool, error)
+		Apply(x, y interface{}, options ...reflection.Option) (bool, error)
 	}
 
 	sorter struct {
-		f interface{}
-		v reflect.Value
-		t reflect.Type
+		f          interface{}
+		v          reflect.Value
+		t          reflect.Type
+		comparator bool
 	}
 )
 
@@ -29,33 +30,39 @@ func IsSorter(f interface{}) bool {
 	return t.Kind() == reflect.Func &&
 		t.NumIn() == 2 && t.NumOut() == 1 &&
 		t.In(0).String() == t.In(1).String() &&
-		t.Out(0).Kind() == reflect.Bool
+		(t.Out(0).Kind() == reflect.Bool || t.Out(0).Kind() == reflect.Int)
 }
 
 func NewSorter(f interface{}) (Sorter, errors.Error) {
 	if !IsSorter(f) {
 		return nil, InvalidSorter
 	}
+	t := reflect.TypeOf(f)
 	return &sorter{
-		f: f,
-		v: reflect.ValueOf(f),
-		t: reflect.TypeOf(f),
+		f:          f,
+		v:          reflect.ValueOf(f),
+		t:          t,
+		comparator: t.Out(0).Kind() == reflect.Int,
 	}, nil
 }
 
-func (s *sorter) Apply(x, y interface{}) (bool, error) {
+func (s *sorter) Apply(x, y interface{}, options ...reflection.Option) (bool, error) {
 	var (
 		vx, vy reflect.Value
 	)
 	if err := func() error {
 		var err error
-		if vx, err = reflection.ConvertShallow(x, s.t.In(0)); err != nil {
+		if vx, err = reflection.ConvertShallow(x, s.t.In(0), options...); err != nil {
 			return err
 		}
-		vy, err = reflection.ConvertShallow(y, s.t.In(1))
+		vy, err = reflection.ConvertShallow(y, s.t.In(1), options...)
 		return err
 	}(); err != nil {
-		return false, errors.NewError().SetCode(errors.Conversion).SetError(fmt.Errorf("invalid argument for sorter: %v", err))
+		return false, errors.NewError().SetCode(errors.Conversion).SetError(fmt.Errorf("invalid argument for sorter: %w", err))
+	}
+	r := s.v.Call([]reflect.Value{vx, vy})[0]
+	if s.comparator {
+		return r.Int() < 0, nil
 	}
-	return s.v.Call([]reflect.Value{vx, vy})[0].Bool(), nil
+	return r.Bool(), nil
 }
@@ -0,0 +1,460 @@
+package functions
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"tools/pkg/errors"
+	"tools/pkg/functions/iterator"
+)
+
+type (
+	// SourceOption changes option of structured sources
+	SourceOption func(*sourceConfig)
+
+	sourceConfig struct {
+		recordType reflect.Type
+		comma      rune
+		lazyQuotes bool
+		columns    []string
+	}
+
+	// PositionError is an error with position in source
+	PositionError interface {
+		error
+		// Line is 1-based line number
+		Line() int
+		// Column is 1-based column number, 0 if unknown
+		Column() int
+	}
+
+	positionError struct {
+		line   int
+		column int
+		err    error
+	}
+)
+
+func (s *positionError) Line() int     { return s.line }
+func (s *positionError) Column() int   { return s.column }
+func (s *positionError) Unwrap() error { return s.err }
+func (s *positionError) Error() string {
+	return fmt.Sprintf("line %d column %d: %v", s.line, s.column, s.err)
+}
+
+func newPositionError(line, column int, err error) error {
+	return errors.NewError().SetCode(errors.Parse).SetError(&positionError{
+		line:   line,
+		column: column,
+		err:    err,
+	})
+}
+
+// WithRecordType decodes records into the type of v instead of map[string]interface{}.
+// v is a struct or a pointer to struct, stream yields pointers if v is a pointer
+func WithRecordType(v interface{}) SourceOption {
+	return func(s *sourceConfig) {
+		s.recordType = reflect.TypeOf(v)
+	}
+}
+
+// WithComma specifies field delimiter of CSV.
+// default: ','
+func WithComma(r rune) SourceOption {
+	return func(s *sourceConfig) {
+		s.comma = r
+	}
+}
+
+// WithColumns names columns of CSV.
+// the first row is treated as a record, not a header
+func WithColumns(columns ...string) SourceOption {
+	return func(s *sourceConfig) {
+		s.columns = columns
+	}
+}
+
+// WithLazyQuotes allows quotes in unquoted fields and non-doubled quotes in quoted fields of CSV.
+// default: false, true for TSV
+func WithLazyQuotes(v bool) SourceOption {
+	return func(s *sourceConfig) {
+		s.lazyQuotes = v
+	}
+}
+
+func newSourceConfig(options ...SourceOption) *sourceConfig {
+	c := &sourceConfig{
+		comma: ',',
+	}
+	for _, opt := range options {
+		opt(c)
+	}
+	return c
+}
+
+// validateStructRecord returns error if record type is specified but not a struct or a pointer to struct
+func (s *sourceConfig) validateStructRecord() error {
+	if s.recordType == nil {
+		return nil
+	}
+	t := s.recordType
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("record type must be a struct: %v", s.recordType))
+	}
+	return nil
+}
+
+// newRecord returns pointer to new record
+func (s *sourceConfig) newRecord() reflect.Value {
+	if s.recordType == nil {
+		return reflect.New(reflect.TypeOf(map[string]interface{}{}))
+	}
+	if s.recordType.Kind() == reflect.Ptr {
+		return reflect.New(s.recordType.Elem())
+	}
+	return reflect.New(s.recordType)
+}
+
+// yield converts pointer from newRecord into stream element
+func (s *sourceConfig) yield(v reflect.Value) interface{} {
+	if s.recordType != nil && s.recordType.Kind() == reflect.Ptr {
+		return v.Interface()
+	}
+	return v.Elem().Interface()
+}
+
+// NewJSONLinesSourceStream creates a stream yields a record per line of JSON Lines.
+// blank lines are skipped
+func NewJSONLinesSourceStream(r io.Reader, options ...SourceOption) Stream {
+	conf := newSourceConfig(options...)
+	if err := conf.validateStructRecord(); err != nil {
+		return NewNilStream(err)
+	}
+	var (
+		br   = bufio.NewReader(r)
+		line int
+	)
+	return NewStream(iterator.MustNew(iterator.Func(func() (interface{}, error) {
+		for {
+			b, err := br.ReadBytes('\n')
+			if len(b) == 0 && err == io.EOF {
+				return nil, iterator.EOI
+			}
+			if err != nil && err != io.EOF {
+				return nil, errors.NewError().SetCode(errors.IO).SetError(err)
+			}
+			line++
+			if len(bytes.TrimSpace(b)) == 0 {
+				continue
+			}
+			v := conf.newRecord()
+			if err := json.Unmarshal(b, v.Interface()); err != nil {
+				return nil, errors.NewElementError(newPositionError(line, jsonErrorOffset(err), err), string(bytes.TrimSpace(b)))
+			}
+			return conf.yield(v), nil
+		}
+	})))
+}
+
+// jsonErrorOffset returns offset of error from encoding/json
+func jsonErrorOffset(err error) int {
+	switch err := err.(type) {
+	case *json.SyntaxError:
+		return int(err.Offset)
+	case *json.UnmarshalTypeError:
+		return int(err.Offset)
+	}
+	return 0
+}
+
+// NewJSONArraySourceStream creates a stream yields elements of a top-level JSON array.
+// elements are decoded one by one without reading the whole array.
+// an element that cannot be decoded into the record is an element error, malformed JSON ends the stream
+func NewJSONArraySourceStream(r io.Reader, options ...SourceOption) Stream {
+	var (
+		conf    = newSourceConfig(options...)
+		pr      = newPositionReader(r)
+		dec     = json.NewDecoder(pr)
+		started bool
+		isEOI   bool
+	)
+	if err := conf.validateStructRecord(); err != nil {
+		return NewNilStream(err)
+	}
+	// positionError returns error at 0-based byte offset
+	positionError := func(err error, offset int64) error {
+		if x := jsonErrorOffset(err); x > 0 {
+			offset += int64(x) - 1
+		}
+		line, column := pr.position(offset)
+		return newPositionError(line, column, err)
+	}
+	fail := func(err error, offset int64) (interface{}, error) {
+		isEOI = true
+		return nil, positionError(err, offset)
+	}
+	return NewStream(iterator.MustNew(iterator.Func(func() (interface{}, error) {
+		if isEOI {
+			return nil, iterator.EOI
+		}
+		if !started {
+			started = true
+			t, err := dec.Token()
+			if err != nil {
+				return fail(err, 0)
+			}
+			if d, ok := t.(json.Delim); !ok || d != '[' {
+				return fail(fmt.Errorf("top-level value is not an array"), 0)
+			}
+		}
+		if !dec.More() {
+			isEOI = true
+			if _, err := dec.Token(); err != nil {
+				return fail(err, 0)
+			}
+			return nil, iterator.EOI
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return fail(err, 0)
+		}
+		end := dec.InputOffset()
+		v := conf.newRecord()
+		if err := json.Unmarshal(raw, v.Interface()); err != nil {
+			err := positionError(err, end-int64(len(raw)))
+			pr.forget(end)
+			return nil, errors.NewElementError(err, string(raw))
+		}
+		pr.forget(end)
+		return conf.yield(v), nil
+	})))
+}
+
+type (
+	// positionReader records line breaks to convert offset into line and column
+	positionReader struct {
+		r        io.Reader
+		read     int64
+		lines    int
+		newlines []int64
+	}
+)
+
+func newPositionReader(r io.Reader) *positionReader {
+	return &positionReader{
+		r:        r,
+		newlines: []int64{},
+	}
+}
+
+func (s *positionReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	for i := 0; i < n; i++ {
+		if p[i] == '\n' {
+			s.newlines = append(s.newlines, s.read+int64(i))
+		}
+	}
+	s.read += int64(n)
+	return n, err
+}
+
+// forget drops line breaks before offset except the last one
+func (s *positionReader) forget(offset int64) {
+	var i int
+	for i < len(s.newlines)-1 && s.newlines[i+1] < offset {
+		i++
+	}
+	s.lines += i
+	s.newlines = s.newlines[i:]
+}
+
+// position returns 1-based line and column of offset
+func (s *positionReader) position(offset int64) (int, int) {
+	var (
+		line      = s.lines + 1
+		lineStart int64
+	)
+	for _, x := range s.newlines {
+		if x >= offset {
+			break
+		}
+		line++
+		lineStart = x + 1
+	}
+	return line, int(offset-lineStart) + 1
+}
+
+// NewCSVSourceStream creates a stream yields a record per row of CSV.
+// the first row is a header that names columns unless WithColumns is specified.
+// fields of struct record are matched by `csv` tag or field name
+func NewCSVSourceStream(r io.Reader, options ...SourceOption) Stream {
+	conf := newSourceConfig(options...)
+	if err := conf.validateStructRecord(); err != nil {
+		return NewNilStream(err)
+	}
+	cr := csv.NewReader(r)
+	cr.Comma = conf.comma
+	cr.LazyQuotes = conf.lazyQuotes
+	var (
+		columns []string
+		fields  []*taggedField
+		isEOI   bool
+	)
+	fail := func(err error) (interface{}, error) {
+		isEOI = true
+		if e, ok := err.(*csv.ParseError); ok {
+			return nil, newPositionError(e.Line, e.Column, e.Err)
+		}
+		return nil, errors.NewError().SetCode(errors.IO).SetError(err)
+	}
+	return NewStream(iterator.MustNew(iterator.Func(func() (interface{}, error) {
+		if isEOI {
+			return nil, iterator.EOI
+		}
+		if columns == nil {
+			if conf.columns != nil {
+				columns = conf.columns
+			} else {
+				header, err := cr.Read()
+				if err == io.EOF {
+					isEOI = true
+					return nil, iterator.EOI
+				}
+				if err != nil {
+					return fail(err)
+				}
+				columns = append([]string{}, header...)
+			}
+			fields = newCSVFields(conf.recordType, columns)
+		}
+		row, err := cr.Read()
+		if err == io.EOF {
+			isEOI = true
+			return nil, iterator.EOI
+		}
+		if err != nil {
+			return fail(err)
+		}
+		v := conf.newRecord()
+		if conf.recordType == nil {
+			m := make(map[string]interface{}, len(columns))
+			for i, c := range columns {
+				if i < len(row) {
+					m[c] = row[i]
+				}
+			}
+			v.Elem().Set(reflect.ValueOf(m))
+			return conf.yield(v), nil
+		}
+		for i, f := range fields {
+			if f == nil || i >= len(row) {
+				continue
+			}
+			if err := f.set(v.Elem(), row[i]); err != nil {
+				line, column := cr.FieldPos(i)
+				return nil, errors.NewElementError(newPositionError(line, column, fmt.Errorf("%s: %w", columns[i], err)), row)
+			}
+		}
+		return conf.yield(v), nil
+	})))
+}
+
+// NewTSVSourceStream creates a stream yields a record per row of TSV.
+// same as NewCSVSourceStream but fields are separated by tab
+func NewTSVSourceStream(r io.Reader, options ...SourceOption) Stream {
+	return NewCSVSourceStream(r, append([]SourceOption{
+		WithComma('\t'),
+		WithLazyQuotes(true),
+	}, options...)...)
+}
+
+type (
+	// taggedField is a field of struct matched by tag, shared by CSV and SQL
+	taggedField struct {
+		index []int
+	}
+)
+
+// newCSVFields returns fields of struct for each column, nil if not found
+func newCSVFields(t reflect.Type, columns []string) []*taggedField {
+	return newTaggedFields(t, "csv", columns)
+}
+
+// newTaggedFields returns fields of struct for each column matched by tag or field name, nil if not found.
+// return nil if t is not a struct or a pointer to struct
+func newTaggedFields(t reflect.Type, tagName string, columns []string) []*taggedField {
+	if t == nil {
+		return nil
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	fields := make([]*taggedField, len(columns))
+	for i, c := range columns {
+		for j := 0; j < t.NumField(); j++ {
+			f := t.Field(j)
+			if f.PkgPath != "" {
+				continue
+			}
+			name := f.Name
+			if tag, ok := f.Tag.Lookup(tagName); ok {
+				if tag == "-" {
+					continue
+				}
+				name = tag
+			}
+			if strings.EqualFold(name, c) {
+				fields[i] = &taggedField{index: f.Index}
+				break
+			}
+		}
+	}
+	return fields
+}
+
+func (s *taggedField) set(v reflect.Value, x string) error {
+	f := v.FieldByIndex(s.index)
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(x)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(x)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(x, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(x, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		i, err := strconv.ParseFloat(x, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetFloat(i)
+	default:
+		return fmt.Errorf("unsupported field type %v", f.Type())
+	}
+	return nil
+}
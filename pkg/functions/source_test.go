@@ -0,0 +1,249 @@
+package functions_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"tools/pkg/errors"
+	"tools/pkg/functions"
+	"tools/pkg/functions/iterator"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type (
+	sourceTestcase struct {
+		Comment string
+		Source  func() functions.Stream
+		Result  []interface{}
+		Line    int
+		Column  int
+		Invalid bool
+	}
+)
+
+func (s *sourceTestcase) Test(t *testing.T) {
+	st := s.Source()
+	if s.Invalid {
+		if err := st.Err(); errors.CodeOf(err) != errors.Validate {
+			t.Errorf("should be validate error: %v", err)
+		}
+		return
+	}
+	actual, err := iterator.ToSlice(st)
+	if s.Line > 0 {
+		if err == nil {
+			t.Fatal("should be error")
+		}
+		if pos := fmt.Sprintf("line %d column %d:", s.Line, s.Column); !strings.Contains(err.Error(), pos) {
+			t.Errorf("not expected position: %v", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(actual, s.Result) {
+		t.Errorf("not expected result:\n  actual(%#v)\nexpected(%#v)", actual, s.Result)
+	}
+}
+
+func TestStructuredSource(t *testing.T) {
+	type Row struct {
+		Name string `csv:"name"`
+		Age  int
+		Rate float64 `csv:"rate"`
+	}
+
+	testcases := []*sourceTestcase{
+		{
+			Comment: "jsonl-map",
+			Source: func() functions.Stream {
+				return functions.NewJSONLinesSourceStream(strings.NewReader(`{"name":"Stela","region":"Romania"}
+
+{"name":"Aud","region":"Norway"}
+`))
+			},
+			Result: []interface{}{
+				map[string]interface{}{"name": "Stela", "region": "Romania"},
+				map[string]interface{}{"name": "Aud", "region": "Norway"},
+			},
+		},
+		{
+			Comment: "jsonl-struct",
+			Source: func() functions.Stream {
+				return functions.NewJSONLinesSourceStream(strings.NewReader(`{"name":"Stela","region":"Romania"}
+{"name":"Aud","region":"Norway"}`), functions.WithRecordType(Person{}))
+			},
+			Result: []interface{}{
+				Person{Name: "Stela", Region: "Romania"},
+				Person{Name: "Aud", Region: "Norway"},
+			},
+		},
+		{
+			Comment: "jsonl-syntax-error",
+			Source: func() functions.Stream {
+				return functions.NewJSONLinesSourceStream(strings.NewReader(`{"name":"Stela"}
+{"name":x}`))
+			},
+			Line:   2,
+			Column: 9,
+		},
+		{
+			Comment: "json-array-pointer",
+			Source: func() functions.Stream {
+				return functions.NewJSONArraySourceStream(strings.NewReader(`[
+  {"name":"Stela","region":"Romania"},
+  {"name":"Aud","region":"Norway"}
+]`), functions.WithRecordType(&Person{}))
+			},
+			Result: []interface{}{
+				&Person{Name: "Stela", Region: "Romania"},
+				&Person{Name: "Aud", Region: "Norway"},
+			},
+		},
+		{
+			Comment: "json-array-empty",
+			Source: func() functions.Stream {
+				return functions.NewJSONArraySourceStream(strings.NewReader(`[]`))
+			},
+			Result: []interface{}{},
+		},
+		{
+			Comment: "json-array-syntax-error",
+			Source: func() functions.Stream {
+				return functions.NewJSONArraySourceStream(strings.NewReader(`[
+  {"name":"Stela"},
+  {"name":"Aud" x}
+]`))
+			},
+			Line:   3,
+			Column: 17,
+		},
+		{
+			Comment: "json-array-type-error",
+			Source: func() functions.Stream {
+				return functions.NewJSONArraySourceStream(strings.NewReader(`[
+  {"name":"Stela"},
+  {"name":1},
+  {"name":"Aud"}
+]`), functions.WithRecordType(Person{}))
+			},
+			Line:   3,
+			Column: 11,
+		},
+		{
+			Comment: "jsonl-not-struct",
+			Source: func() functions.Stream {
+				return functions.NewJSONLinesSourceStream(strings.NewReader(`{"name":"Stela"}`), functions.WithRecordType(0))
+			},
+			Invalid: true,
+		},
+		{
+			Comment: "json-array-not-struct",
+			Source: func() functions.Stream {
+				return functions.NewJSONArraySourceStream(strings.NewReader(`[{"name":"Stela"}]`), functions.WithRecordType([]string{}))
+			},
+			Invalid: true,
+		},
+		{
+			Comment: "csv-map",
+			Source: func() functions.Stream {
+				return functions.NewCSVSourceStream(strings.NewReader("name,region\nStela,Romania\n\"Aud, R\",Norway\n"))
+			},
+			Result: []interface{}{
+				map[string]interface{}{"name": "Stela", "region": "Romania"},
+				map[string]interface{}{"name": "Aud, R", "region": "Norway"},
+			},
+		},
+		{
+			Comment: "csv-columns",
+			Source: func() functions.Stream {
+				return functions.NewCSVSourceStream(strings.NewReader("Stela,Romania\n"), functions.WithColumns("name", "region"))
+			},
+			Result: []interface{}{
+				map[string]interface{}{"name": "Stela", "region": "Romania"},
+			},
+		},
+		{
+			Comment: "tsv-struct",
+			Source: func() functions.Stream {
+				return functions.NewTSVSourceStream(strings.NewReader("name\tage\trate\tignored\nStela\t20\t1.5\tx\n"), functions.WithRecordType(Row{}))
+			},
+			Result: []interface{}{
+				Row{Name: "Stela", Age: 20, Rate: 1.5},
+			},
+		},
+		{
+			Comment: "csv-conversion-error",
+			Source: func() functions.Stream {
+				return functions.NewCSVSourceStream(strings.NewReader("name,age\nStela,20\nAud,x\n"), functions.WithRecordType(Row{}))
+			},
+			Line:   3,
+			Column: 5,
+		},
+		{
+			Comment: "csv-not-struct",
+			Source: func() functions.Stream {
+				return functions.NewCSVSourceStream(strings.NewReader("name,age\nStela,20\n"), functions.WithRecordType(0))
+			},
+			Invalid: true,
+		},
+		{
+			Comment: "tsv-not-struct",
+			Source: func() functions.Stream {
+				return functions.NewTSVSourceStream(strings.NewReader("name\tage\nStela\t20\n"), functions.WithRecordType(new(string)))
+			},
+			Invalid: true,
+		},
+		{
+			Comment: "tsv-strict-quotes",
+			Source: func() functions.Stream {
+				return functions.NewTSVSourceStream(strings.NewReader("name\tage\nSt\"ela\t20\n"), functions.WithLazyQuotes(false))
+			},
+			Line:   2,
+			Column: 3,
+		},
+		{
+			Comment: "tsv-lazy-quotes",
+			Source: func() functions.Stream {
+				return functions.NewTSVSourceStream(strings.NewReader("name\tage\nSt\"ela\t20\n"))
+			},
+			Result: []interface{}{
+				map[string]interface{}{"name": "St\"ela", "age": "20"},
+			},
+		},
+		{
+			Comment: "csv-parse-error",
+			Source: func() functions.Stream {
+				return functions.NewCSVSourceStream(strings.NewReader("name,age\nStela,20,1\n"))
+			},
+			Line:   2,
+			Column: 1,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.Comment, func(t *testing.T) {
+			tt.Test(t)
+		})
+	}
+}
+
+func TestJSONArraySourceSkip(t *testing.T) {
+	st := functions.NewStream(functions.NewJSONArraySourceStream(strings.NewReader(`[
+  {"name":"Stela"},
+  {"name":1},
+  {"name":"Aud"}
+]`), functions.WithRecordType(Person{})), functions.WithErrorPolicy(functions.ErrorSkip))
+	var r []Person
+	if err := st.As(&r); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []Person{{Name: "Stela"}, {Name: "Aud"}}; !cmp.Equal(r, expected) {
+		t.Errorf("  actual: %#v\nexpected: %#v", r, expected)
+	}
+	if err := st.Err(); err == nil || !strings.Contains(err.Error(), "line 3 column 11") {
+		t.Errorf("not expected error: %v", err)
+	}
+}
@@ -0,0 +1,313 @@
+package functions
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"tools/pkg/errors"
+	"tools/pkg/functions/iterator"
+)
+
+// NewSQLSourceStream creates a stream yields a record per row.
+// record is map[string]interface{} by column name unless WithRecordType,
+// fields of struct record are matched by `db` tag or field name.
+// rows are closed at the end of the stream or on error
+func NewSQLSourceStream(rows *sql.Rows, options ...SourceOption) Stream {
+	var (
+		conf    = newSourceConfig(options...)
+		columns []string
+		fields  []*taggedField
+		closed  bool
+	)
+	if err := conf.validateStructRecord(); err != nil {
+		_ = rows.Close()
+		return NewNilStream(err)
+	}
+	var fail = func(err error) (interface{}, error) {
+		closed = true
+		_ = rows.Close()
+		return nil, errors.NewError().SetCode(errors.IO).SetError(err)
+	}
+	return NewStream(iterator.MustNew(iterator.Func(func() (interface{}, error) {
+		if closed {
+			return nil, iterator.EOI
+		}
+		if columns == nil {
+			c, err := rows.Columns()
+			if err != nil {
+				return fail(err)
+			}
+			columns = c
+			fields = newTaggedFields(conf.recordType, "db", columns)
+		}
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return fail(err)
+			}
+			closed = true
+			if err := rows.Close(); err != nil {
+				return nil, errors.NewError().SetCode(errors.IO).SetError(err)
+			}
+			return nil, iterator.EOI
+		}
+		v := conf.newRecord()
+		dest := make([]interface{}, len(columns))
+		if conf.recordType == nil {
+			for i := range dest {
+				dest[i] = new(interface{})
+			}
+			if err := rows.Scan(dest...); err != nil {
+				return fail(err)
+			}
+			m := v.Elem()
+			m.Set(reflect.MakeMap(m.Type()))
+			for i, c := range columns {
+				x := *(dest[i].(*interface{}))
+				if b, ok := x.([]byte); ok {
+					x = string(b)
+				}
+				m.SetMapIndex(reflect.ValueOf(c), reflect.ValueOf(&x).Elem())
+			}
+			return conf.yield(v), nil
+		}
+		for i, f := range fields {
+			if f == nil {
+				dest[i] = new(interface{})
+				continue
+			}
+			dest[i] = v.Elem().FieldByIndex(f.index).Addr().Interface()
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return fail(err)
+		}
+		return conf.yield(v), nil
+	})))
+}
+
+// NewSQLQuerySourceStream creates a stream yields a record per row of the query, see NewSQLSourceStream
+func NewSQLQuerySourceStream(ctx context.Context, db *sql.DB, query string, args []interface{}, options ...SourceOption) Stream {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return NewNilStream(errors.NewError().SetCode(errors.IO).SetError(err))
+	}
+	return NewSQLSourceStream(rows, options...)
+}
+
+var sqlIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateSQLIdentifiers returns error if any of names cannot be written into a statement as is.
+// names may be qualified by dots, e.g. `schema.table`
+func validateSQLIdentifiers(names ...string) error {
+	for _, name := range names {
+		for _, x := range strings.Split(name, ".") {
+			if !sqlIdentifier.MatchString(x) {
+				return errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("invalid identifier: %q", name))
+			}
+		}
+	}
+	return nil
+}
+
+type (
+	// SQLSinkOption changes option of SinkSQL
+	SQLSinkOption func(*sqlSink)
+
+	sqlSink struct {
+		db          *sql.DB
+		ctx         context.Context
+		table       string
+		columns     []string
+		batchSize   int
+		placeholder func(int) string
+		batch       []interface{}
+		rows        int
+		// fields are cached for fieldsType
+		fieldsType reflect.Type
+		fields     []*taggedField
+	}
+)
+
+// WithBatchSize inserts n rows by a statement.
+// default: 100
+func WithBatchSize(n int) SQLSinkOption {
+	return func(s *sqlSink) {
+		s.batchSize = n
+	}
+}
+
+// WithPlaceholder specifies placeholder of i-th (1-based) argument, e.g. `$1` for PostgreSQL.
+// default: `?`
+func WithPlaceholder(f func(i int) string) SQLSinkOption {
+	return func(s *sqlSink) {
+		s.placeholder = f
+	}
+}
+
+// WithSQLContext specifies context for transactions
+func WithSQLContext(ctx context.Context) SQLSinkOption {
+	return func(s *sqlSink) {
+		s.ctx = ctx
+	}
+}
+
+// SinkSQL consumes a stream.
+// insert elements into table by batches, every batch is inserted in a transaction.
+// element is a struct, a pointer to struct or a map.
+// columns are derived from the first element unless specified,
+// fields of struct are named by `db` tag or field name.
+// table and columns must be identifiers matching `^[A-Za-z_][A-Za-z0-9_]*$`, table may be qualified by dots.
+// map element with a key out of columns is a conversion error.
+// invoke onError on conversion error of an element and skip it, stop at the first database error.
+// return the error of the stream after inserting
+func SinkSQL(db *sql.DB, table string, columns []string, onError func(error), st Stream, options ...SQLSinkOption) error {
+	s := &sqlSink{
+		db:        db,
+		ctx:       context.Background(),
+		table:     table,
+		columns:   columns,
+		batchSize: 100,
+		placeholder: func(int) string {
+			return "?"
+		},
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+	if s.batchSize < 1 {
+		return errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("invalid batch size: %d", s.batchSize))
+	}
+	if err := validateSQLIdentifiers(append([]string{table}, columns...)...); err != nil {
+		return err
+	}
+	for {
+		x, err := st.Next()
+		if errors.Is(err, iterator.EOI) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		args, err := s.values(x)
+		if err != nil {
+			if onError != nil {
+				onError(err)
+			}
+			continue
+		}
+		s.batch = append(s.batch, args...)
+		s.rows++
+		if s.rows >= s.batchSize {
+			if err := s.flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := s.flush(); err != nil {
+		return err
+	}
+	return st.Err()
+}
+
+// values returns arguments of a row
+func (s *sqlSink) values(x interface{}) ([]interface{}, error) {
+	v := reflect.ValueOf(x)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if len(s.columns) == 0 {
+		columns := taggedColumnsOf(v, "db")
+		if len(columns) == 0 {
+			return nil, errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("no columns from %v", x))
+		}
+		if err := validateSQLIdentifiers(columns...); err != nil {
+			return nil, err
+		}
+		s.columns = columns
+	}
+	args := make([]interface{}, len(s.columns))
+	switch v.Kind() {
+	case reflect.Struct:
+		if s.fieldsType != v.Type() {
+			s.fieldsType = v.Type()
+			s.fields = newTaggedFields(v.Type(), "db", s.columns)
+		}
+		for i, f := range s.fields {
+			if f != nil {
+				args[i] = v.FieldByIndex(f.index).Interface()
+			}
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			if !s.hasColumn(fmt.Sprint(k.Interface())) {
+				return nil, errors.NewError().SetCode(errors.Conversion).SetError(fmt.Errorf("unknown column %v of %v", k.Interface(), x))
+			}
+		}
+		for i, c := range s.columns {
+			if x := v.MapIndex(reflect.ValueOf(c)); x.IsValid() {
+				args[i] = x.Interface()
+			}
+		}
+	default:
+		return nil, errors.NewError().SetCode(errors.Conversion).SetError(fmt.Errorf("cannot insert %v", x))
+	}
+	return args, nil
+}
+
+func (s *sqlSink) hasColumn(name string) bool {
+	for _, c := range s.columns {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// statement returns insert statement for n rows
+func (s *sqlSink) statement(n int) string {
+	var (
+		b    strings.Builder
+		argN int
+	)
+	fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES ", s.table, strings.Join(s.columns, ", "))
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(")
+		for j := range s.columns {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			argN++
+			b.WriteString(s.placeholder(argN))
+		}
+		b.WriteString(")")
+	}
+	return b.String()
+}
+
+// flush inserts the batch in a transaction
+func (s *sqlSink) flush() error {
+	if s.rows == 0 {
+		return nil
+	}
+	defer func() {
+		s.batch = nil
+		s.rows = 0
+	}()
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return errors.NewError().SetCode(errors.IO).SetError(err)
+	}
+	if _, err := tx.ExecContext(s.ctx, s.statement(s.rows), s.batch...); err != nil {
+		_ = tx.Rollback()
+		return errors.NewError().SetCode(errors.IO).SetError(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.NewError().SetCode(errors.IO).SetError(err)
+	}
+	return nil
+}
@@ -0,0 +1,347 @@
+package functions_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"tools/pkg/errors"
+	"tools/pkg/functions"
+	"tools/pkg/functions/iterator"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type (
+	// fakeDB is an in-process database for fakeDriver
+	fakeDB struct {
+		mux       sync.Mutex
+		columns   []string
+		rows      [][]driver.Value
+		committed []fakeExec
+		failExec  int
+		execs     int
+	}
+
+	fakeExec struct {
+		Query string
+		Args  []driver.Value
+	}
+
+	fakeDriver struct{}
+	fakeConn   struct {
+		db      *fakeDB
+		pending []fakeExec
+	}
+	fakeStmt struct {
+		conn  *fakeConn
+		query string
+	}
+	fakeTx   struct{ conn *fakeConn }
+	fakeRows struct {
+		columns []string
+		rows    [][]driver.Value
+	}
+)
+
+var (
+	fakeDBs = map[string]*fakeDB{}
+	fakeMux sync.Mutex
+)
+
+func init() {
+	sql.Register("fake", &fakeDriver{})
+}
+
+func newFakeDB(t *testing.T, db *fakeDB) *sql.DB {
+	fakeMux.Lock()
+	defer fakeMux.Unlock()
+	name := t.Name()
+	fakeDBs[name] = db
+	x, err := sql.Open("fake", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return x
+}
+
+func (*fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeMux.Lock()
+	defer fakeMux.Unlock()
+	return &fakeConn{db: fakeDBs[name]}, nil
+}
+
+func (s *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: s, query: query}, nil
+}
+func (*fakeConn) Close() error                { return nil }
+func (s *fakeConn) Begin() (driver.Tx, error) { return &fakeTx{conn: s}, nil }
+
+func (s *fakeTx) Commit() error {
+	s.conn.db.mux.Lock()
+	defer s.conn.db.mux.Unlock()
+	s.conn.db.committed = append(s.conn.db.committed, s.conn.pending...)
+	s.conn.pending = nil
+	return nil
+}
+
+func (s *fakeTx) Rollback() error {
+	s.conn.pending = nil
+	return nil
+}
+
+func (*fakeStmt) Close() error  { return nil }
+func (*fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	db := s.conn.db
+	db.mux.Lock()
+	defer db.mux.Unlock()
+	db.execs++
+	if db.execs == db.failExec {
+		return nil, fmt.Errorf("exec failed")
+	}
+	s.conn.pending = append(s.conn.pending, fakeExec{
+		Query: s.query,
+		Args:  args,
+	})
+	return driver.RowsAffected(len(args)), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeRows{
+		columns: s.conn.db.columns,
+		rows:    s.conn.db.rows,
+	}, nil
+}
+
+func (s *fakeRows) Columns() []string { return s.columns }
+func (*fakeRows) Close() error        { return nil }
+
+func (s *fakeRows) Next(dest []driver.Value) error {
+	if len(s.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, s.rows[0])
+	s.rows = s.rows[1:]
+	return nil
+}
+
+func TestSQLSource(t *testing.T) {
+	type Row struct {
+		Name string `db:"name"`
+		Age  int
+	}
+
+	testcases := []struct {
+		Comment string
+		Options []functions.SourceOption
+		Result  []interface{}
+	}{
+		{
+			Comment: "map",
+			Result: []interface{}{
+				map[string]interface{}{"name": "Stela", "age": int64(20), "note": nil},
+				map[string]interface{}{"name": "Aud", "age": int64(30), "note": "x"},
+			},
+		},
+		{
+			Comment: "struct",
+			Options: []functions.SourceOption{
+				functions.WithRecordType(&Row{}),
+			},
+			Result: []interface{}{
+				&Row{Name: "Stela", Age: 20},
+				&Row{Name: "Aud", Age: 30},
+			},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.Comment, func(t *testing.T) {
+			db := newFakeDB(t, &fakeDB{
+				columns: []string{"name", "age", "note"},
+				rows: [][]driver.Value{
+					{[]byte("Stela"), int64(20), nil},
+					{"Aud", int64(30), []byte("x")},
+				},
+			})
+			defer db.Close()
+			st := functions.NewSQLQuerySourceStream(context.Background(), db, "SELECT name, age, note FROM people", nil, tt.Options...)
+			result, err := iterator.ToSlice(st)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(result, tt.Result) {
+				t.Errorf("not expected result:\n  actual(%#v)\nexpected(%#v)", result, tt.Result)
+			}
+		})
+	}
+}
+
+func TestSinkSQL(t *testing.T) {
+	type Row struct {
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+		Skip string `db:"-"`
+	}
+	type Other struct {
+		Age  int
+		Name string
+	}
+
+	testcases := []struct {
+		Comment  string
+		Data     interface{}
+		Table    string
+		Columns  []string
+		Options  []functions.SQLSinkOption
+		FailExec int
+		IsError  bool
+		Errors   int
+		Result   []fakeExec
+	}{
+		{
+			Comment: "struct-batch",
+			Data:    []Row{{Name: "a", Age: 1}, {Name: "b", Age: 2}, {Name: "c", Age: 3}},
+			Options: []functions.SQLSinkOption{
+				functions.WithBatchSize(2),
+			},
+			Result: []fakeExec{
+				{
+					Query: "INSERT INTO people (name, age) VALUES (?, ?), (?, ?)",
+					Args:  []driver.Value{"a", int64(1), "b", int64(2)},
+				},
+				{
+					Query: "INSERT INTO people (name, age) VALUES (?, ?)",
+					Args:  []driver.Value{"c", int64(3)},
+				},
+			},
+		},
+		{
+			Comment: "map-columns-placeholder",
+			Data: []map[string]interface{}{
+				{"name": "a", "age": 1},
+				{"name": "b"},
+				{"name": "c", "x": 0},
+			},
+			Columns: []string{"name", "age"},
+			Options: []functions.SQLSinkOption{
+				functions.WithPlaceholder(func(i int) string { return fmt.Sprintf("$%d", i) }),
+			},
+			Result: []fakeExec{
+				{
+					Query: "INSERT INTO people (name, age) VALUES ($1, $2), ($3, $4)",
+					Args:  []driver.Value{"a", int64(1), "b", nil},
+				},
+			},
+			Errors: 1,
+		},
+		{
+			Comment: "struct-types",
+			Data:    []interface{}{Row{Name: "a", Age: 1}, &Other{Name: "b", Age: 2}, Row{Name: "c", Age: 3}},
+			Result: []fakeExec{
+				{
+					Query: "INSERT INTO people (name, age) VALUES (?, ?), (?, ?), (?, ?)",
+					Args:  []driver.Value{"a", int64(1), "b", int64(2), "c", int64(3)},
+				},
+			},
+		},
+		{
+			Comment: "map-invalid-key",
+			Data: []map[string]interface{}{
+				{"name": "a", "age) VALUES (1, 2); --": 1},
+				{"name": "b", "age": 2},
+				{"name": "c", "age": 3},
+				{"name": "d", "note": "x"},
+			},
+			Result: []fakeExec{
+				{
+					Query: "INSERT INTO people (age, name) VALUES (?, ?), (?, ?)",
+					Args:  []driver.Value{int64(2), "b", int64(3), "c"},
+				},
+			},
+			Errors: 2,
+		},
+		{
+			Comment: "invalid-table",
+			Data:    []Row{{Name: "a", Age: 1}},
+			Table:   "people; DROP TABLE people",
+			IsError: true,
+		},
+		{
+			Comment: "invalid-column",
+			Data:    []Row{{Name: "a", Age: 1}},
+			Columns: []string{"name", "age)"},
+			IsError: true,
+		},
+		{
+			Comment: "qualified-table",
+			Data:    []Row{{Name: "a", Age: 1}},
+			Table:   "main.people",
+			Result: []fakeExec{
+				{
+					Query: "INSERT INTO main.people (name, age) VALUES (?, ?)",
+					Args:  []driver.Value{"a", int64(1)},
+				},
+			},
+		},
+		{
+			Comment:  "rollback",
+			Data:     []Row{{Name: "a", Age: 1}, {Name: "b", Age: 2}, {Name: "c", Age: 3}},
+			Options:  []functions.SQLSinkOption{functions.WithBatchSize(2)},
+			FailExec: 1,
+			IsError:  true,
+			Result:   nil,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.Comment, func(t *testing.T) {
+			fdb := &fakeDB{failExec: tt.FailExec}
+			db := newFakeDB(t, fdb)
+			defer db.Close()
+			table := tt.Table
+			if table == "" {
+				table = "people"
+			}
+			var errs int
+			st := functions.NewStream(iterator.MustNew(tt.Data))
+			err := functions.SinkSQL(db, table, tt.Columns, func(err error) {
+				errs++
+			}, st, tt.Options...)
+			if tt.IsError {
+				if err == nil {
+					t.Error("should be error")
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if errs != tt.Errors {
+				t.Errorf("not expected errors: %d", errs)
+			}
+			if !cmp.Equal(fdb.committed, tt.Result) {
+				t.Errorf("not expected result:\n  actual(%#v)\nexpected(%#v)", fdb.committed, tt.Result)
+			}
+		})
+	}
+}
+
+func TestSinkSQLStreamError(t *testing.T) {
+	fdb := &fakeDB{}
+	db := newFakeDB(t, fdb)
+	defer db.Close()
+	st := functions.NewStream(functions.NewJSONLinesSourceStream(strings.NewReader("{\"name\": \"a\"}\n{\"name\"\n")), functions.WithErrorPolicy(functions.ErrorSkip))
+	err := functions.SinkSQL(db, "people", nil, nil, st)
+	var m *errors.MultiError
+	if !errors.As(err, &m) || m.Len() != 1 {
+		t.Errorf("should be MultiError: %v", err)
+	}
+	if len(fdb.committed) != 1 {
+		t.Errorf("not expected result: %#v", fdb.committed)
+	}
+}
@@ -0,0 +1,156 @@
+package throttle
+
+import (
+	"fmt"
+	"time"
+	"tools/pkg/clock"
+	"tools/pkg/conv/reflection"
+	"tools/pkg/errors"
+	"tools/pkg/functions/executor"
+	"tools/pkg/functions/iterator"
+	"tools/pkg/log"
+)
+
+var (
+	InvalidRate  = errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("invalid rate"))
+	InvalidBurst = errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("invalid burst"))
+)
+
+type (
+	// Executor is throttle executor.
+	// limits pulling elements by token bucket
+	Executor struct {
+		hooks  executor.Hookable
+		iter   iterator.Iterator
+		clock  clock.Clock
+		rate   float64
+		burst  int
+		onWait func(time.Duration)
+
+		conversion []reflection.Option
+	}
+	// Option changes option of Executor
+	Option func(*Executor)
+)
+
+// WithHook add hook
+func WithHook(ht executor.HookType, h interface{}) Option {
+	return func(s *Executor) {
+		s.hooks.AddHook(ht, h)
+	}
+}
+
+// WithLogger logs progress at debug level with field stage=throttle
+func WithLogger(l log.Logger) Option {
+	return func(s *Executor) {
+		s.iter = executor.AddLogHooks(s.hooks, l.With("stage", "throttle"), s.iter)
+	}
+}
+
+// WithConversion specifies options of conversion of arguments of hooks
+func WithConversion(options ...reflection.Option) Option {
+	return func(s *Executor) {
+		s.conversion = append(s.conversion, options...)
+		s.hooks.SetConversion(s.conversion...)
+	}
+}
+
+// WithClock specifies clock to refill tokens and wait.
+// default: system clock
+func WithClock(c clock.Clock) Option {
+	return func(s *Executor) {
+		s.clock = c
+	}
+}
+
+// WithOnWait invokes f with the duration waited for a token of every element
+func WithOnWait(f func(time.Duration)) Option {
+	return func(s *Executor) {
+		s.onWait = f
+	}
+}
+
+// NewExecutor creates Executor.
+// rate is number of tokens refilled per second, burst is capacity of the bucket
+func NewExecutor(iter iterator.Iterator, rate float64, burst int, options ...Option) (*Executor, errors.Error) {
+	if rate <= 0 {
+		return nil, InvalidRate
+	}
+	if burst < 1 {
+		return nil, InvalidBurst
+	}
+	executor := &Executor{
+		hooks: executor.NewHookable(),
+		iter:  iter,
+		clock: clock.New(),
+		rate:  rate,
+		burst: burst,
+	}
+	for _, opt := range options {
+		opt(executor)
+	}
+	return executor, nil
+}
+
+func (s *Executor) Execute() iterator.Iterator {
+	s.hooks.Execute(executor.BeforeHook, s.iter)
+	b := &bucket{
+		clock:  s.clock,
+		rate:   s.rate,
+		burst:  float64(s.burst),
+		tokens: float64(s.burst),
+		last:   s.clock.Now(),
+	}
+	return iterator.MustNew(iterator.Func(func() (interface{}, error) {
+		x, err := s.iter.Next()
+		if err != nil {
+			if errors.Is(err, iterator.EOI) {
+				s.hooks.Execute(executor.AfterHook)
+			}
+			return nil, err
+		}
+		s.hooks.Execute(executor.RunningHook, x)
+		waited := b.take()
+		if s.onWait != nil {
+			s.onWait(waited)
+		}
+		s.hooks.Execute(executor.RunningResultHook, x)
+		return x, nil
+	}))
+}
+
+type (
+	bucket struct {
+		clock  clock.Clock
+		rate   float64
+		burst  float64
+		tokens float64
+		last   time.Time
+	}
+)
+
+func (s *bucket) refill() {
+	now := s.clock.Now()
+	s.tokens += now.Sub(s.last).Seconds() * s.rate
+	if s.tokens > s.burst {
+		s.tokens = s.burst
+	}
+	s.last = now
+}
+
+// take consumes a token, waits until a token is available.
+// returns waited duration
+func (s *bucket) take() time.Duration {
+	s.refill()
+	var d time.Duration
+	if s.tokens < 1 {
+		d = time.Duration((1 - s.tokens) / s.rate * float64(time.Second))
+		s.clock.Sleep(d)
+		s.refill()
+	}
+	s.tokens--
+	if s.tokens < 0 {
+		s.tokens = 0
+	}
+	return d
+}
@@ -1,15 +1,20 @@
 package functions
 
 import (
-	"bufio"
+	"fmt"
 	"io"
+	"os"
+	"tools/pkg/errors"
 	"tools/pkg/functions/filter"
 	"tools/pkg/functions/flat"
 	"tools/pkg/functions/fold"
 	"tools/pkg/functions/iterator"
 	"tools/pkg/functions/lift"
 	"tools/pkg/functions/mapper"
+	"tools/pkg/functions/sample"
 	"tools/pkg/functions/sorter"
+	"tools/pkg/io/compress"
+	"tools/pkg/io/read"
 )
 
 type (
@@ -103,6 +108,17 @@ const (
 	FlatScriptType
 	// LiftScriptType for Lift
 	LiftScriptType
+	// FlatMapScriptType for FlatMap
+	FlatMapScriptType
+	// SampleReservoirScriptType for SampleReservoir.
+	// instance is k, seed is specified by sample.WithSeed
+	SampleReservoirScriptType
+	// SampleFractionScriptType for SampleFraction.
+	// instance is p, seed is specified by sample.WithSeed
+	SampleFractionScriptType
+	// SampleStratifiedScriptType for SampleStratified.
+	// instance is key, stratum size and seed are specified by sample.WithStratumSize and sample.WithSeed
+	SampleStratifiedScriptType
 )
 
 type (
@@ -138,6 +154,14 @@ func (s *streamBuilder) Append(x Script) StreamBuilder {
 			return s.appendFlat
 		case LiftScriptType:
 			return s.appendLift
+		case FlatMapScriptType:
+			return s.appendFlatMap
+		case SampleReservoirScriptType:
+			return s.appendSampleReservoir
+		case SampleFractionScriptType:
+			return s.appendSampleFraction
+		case SampleStratifiedScriptType:
+			return s.appendSampleStratified
 		}
 		return func(Script) Stream { return s.st }
 	}()(x)
@@ -194,6 +218,16 @@ func (s *streamBuilder) appendFlat(x Script) Stream {
 	return s.st.Flat(opts...)
 }
 
+func (s *streamBuilder) appendFlatMap(x Script) Stream {
+	opts := []flat.Option{}
+	for i := 0; i < x.NumOption(); i++ {
+		if p, ok := x.Option(i).(flat.Option); ok {
+			opts = append(opts, p)
+		}
+	}
+	return s.st.FlatMap(x.Instance(), opts...)
+}
+
 func (s *streamBuilder) appendLift(x Script) Stream {
 	opts := []lift.Option{}
 	for i := 0; i < x.NumOption(); i++ {
@@ -204,43 +238,116 @@ func (s *streamBuilder) appendLift(x Script) Stream {
 	return s.st.Lift(opts...)
 }
 
+func sampleOptions(x Script) []sample.Option {
+	opts := []sample.Option{}
+	for i := 0; i < x.NumOption(); i++ {
+		if p, ok := x.Option(i).(sample.Option); ok {
+			opts = append(opts, p)
+		}
+	}
+	return opts
+}
+
+func (s *streamBuilder) appendSampleReservoir(x Script) Stream {
+	k, ok := x.Instance().(int)
+	if !ok {
+		return NewNilStream(newStreamError(errors.Sample, errMsgInvalidFunction, fmt.Errorf("k is not int: %v", x.Instance())))
+	}
+	return s.st.SampleReservoir(k, sampleOptions(x)...)
+}
+
+func (s *streamBuilder) appendSampleFraction(x Script) Stream {
+	p, ok := x.Instance().(float64)
+	if !ok {
+		return NewNilStream(newStreamError(errors.Sample, errMsgInvalidFunction, fmt.Errorf("p is not float64: %v", x.Instance())))
+	}
+	return s.st.SampleFraction(p, sampleOptions(x)...)
+}
+
+func (s *streamBuilder) appendSampleStratified(x Script) Stream {
+	return s.st.SampleStratified(x.Instance(), sampleOptions(x)...)
+}
+
 func (s *streamBuilder) Build() Stream {
 	return s.st
 }
 
-// NewLineSourceStream creates a stream yields line bytes from reader
-func NewLineSourceStream(r io.Reader) Stream {
-	s := bufio.NewScanner(r)
+// NewLineSourceStream creates a stream yields line bytes from reader.
+// options change how to split reader into records
+func NewLineSourceStream(r io.Reader, options ...read.Option) Stream {
+	iter := read.NewScannerIterator(r, options...)
 	return NewStream(iterator.MustNew(iterator.Func(func() (interface{}, error) {
-		if s.Scan() {
-			return s.Bytes(), nil
+		b, err := iter.Next()
+		if errors.Is(err, read.EOI) {
+			return nil, iterator.EOI
 		}
-		if err := s.Err(); err != nil {
+		if err != nil {
 			return nil, err
 		}
-		return nil, iterator.EOI
+		return b, nil
 	})))
 }
 
-// SinkLineToWriter consumes a stream.
-// write to writer at every element yielded from the stream.
-// invoke onError on writer error
-func SinkLineToWriter(w io.Writer, onError func(error), st Stream) error {
+// NewRecordSourceStream creates a stream yields read.Record from reader.
+// a record knows its byte offset.
+// options change how to split reader into records
+func NewRecordSourceStream(r io.Reader, options ...read.Option) Stream {
+	iter := read.NewScannerIterator(r, options...)
+	return NewStream(iterator.MustNew(iterator.Func(func() (interface{}, error) {
+		x, err := read.NextRecord(iter)
+		if errors.Is(err, read.EOI) {
+			return nil, iterator.EOI
+		}
+		if err != nil {
+			return nil, err
+		}
+		return x, nil
+	})))
+}
+
+// NewFileSourceStream creates a stream yields line bytes from file.
+// gzip, bzip2 and zstd files are decompressed while streaming, detected by magic bytes.
+// file is closed when the stream reaches the end.
+// options change how to split file into records
+func NewFileSourceStream(path string, options ...read.Option) Stream {
+	f, err := os.Open(path)
+	if err != nil {
+		return NewNilStream(errors.NewError().SetCode(errors.IO).SetError(err))
+	}
+	r, err := compress.NewReader(f)
+	if err != nil {
+		_ = f.Close()
+		return NewNilStream(err)
+	}
 	var (
-		s          = bufio.NewWriter(w)
-		useOnError = onError != nil
-		fErr       = func(err error) {
-			if useOnError && err != nil {
-				onError(err)
+		iter      = read.NewScannerIterator(r, options...)
+		isClosed  bool
+		closeFile = func() {
+			if !isClosed {
+				isClosed = true
+				_ = r.Close()
+				_ = f.Close()
 			}
 		}
-		nl = []byte("\n")
 	)
-	return st.Consume(func(x []byte) {
-		if _, err := s.Write(append(x, nl...)); err != nil {
-			fErr(err)
-			return
+	return NewStream(iterator.MustNew(iterator.Func(func() (interface{}, error) {
+		b, err := iter.Next()
+		if errors.Is(err, read.EOI) {
+			closeFile()
+			return nil, iterator.EOI
 		}
-		fErr(s.Flush())
-	})
+		if err != nil {
+			closeFile()
+			return nil, err
+		}
+		return b, nil
+	})))
+}
+
+// SinkLineToWriter consumes a stream.
+// write to writer every element yielded from the stream followed by a newline,
+// output is buffered and flushed at the end, use Sink with NewFlushingEncoder to flush at every element.
+// invoke onError on writer error
+func SinkLineToWriter(w io.Writer, onError func(error), st Stream) error {
+	return Sink(NewLineEncoder(w), onError, st)
 }
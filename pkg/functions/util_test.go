@@ -1,12 +1,20 @@
 package functions_test
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 	"tools/pkg/functions"
 	"tools/pkg/functions/fold"
 	"tools/pkg/functions/iterator"
+	"tools/pkg/functions/sample"
+	"tools/pkg/io/compress"
+	"tools/pkg/io/read"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -154,6 +162,185 @@ func TestStreamBuilder(t *testing.T) {
 				},
 			},
 		},
+		{
+			Comment: "sample",
+			Data:    []int{1, 2, 3, 4, 5, 6},
+			Result:  []interface{}{1, 2, 3, 4, 5, 6},
+			Rows: []row{
+				{
+					T: functions.SampleFractionScriptType,
+					I: 1.0,
+					O: []interface{}{sample.WithSeed(1)},
+				},
+				{
+					T: functions.SampleStratifiedScriptType,
+					I: func(x int) int {
+						return x % 3
+					},
+					O: []interface{}{sample.WithStratumSize(2)},
+				},
+				{
+					T: functions.SampleReservoirScriptType,
+					I: 6,
+				},
+			},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.Comment, func(t *testing.T) {
+			tt.Test(t)
+		})
+	}
+}
+
+type (
+	lineSourceTestcase struct {
+		Comment string
+		Input   string
+		Options []read.Option
+		Result  []string
+		Offsets []int64
+		IsError bool
+	}
+)
+
+func (s *lineSourceTestcase) Test(t *testing.T) {
+	var (
+		result  = []string{}
+		offsets = []int64{}
+	)
+	err := functions.NewRecordSourceStream(strings.NewReader(s.Input), s.Options...).Consume(func(x read.Record) {
+		result = append(result, string(x.Bytes()))
+		offsets = append(offsets, x.Offset())
+	})
+	if s.IsError {
+		if err == nil {
+			t.Error("should be error")
+		}
+		return
+	}
+	if err != nil {
+		t.Error(err)
+	}
+	if !cmp.Equal(result, s.Result) {
+		t.Errorf("  actual: %#v\nexpected: %#v", result, s.Result)
+	}
+	if s.Offsets != nil && !cmp.Equal(offsets, s.Offsets) {
+		t.Errorf("  actual offsets: %v\nexpected offsets: %v", offsets, s.Offsets)
+	}
+}
+
+func TestLineSourceStream(t *testing.T) {
+	longLine := strings.Repeat("x", 100*1024)
+	testcases := []*lineSourceTestcase{
+		{
+			Comment: "lines",
+			Input:   "a\nbc\n\nd",
+			Result:  []string{"a", "bc", "", "d"},
+			Offsets: []int64{0, 2, 5, 6},
+		},
+		{
+			Comment: "too-long-line",
+			Input:   longLine + "\n",
+			IsError: true,
+		},
+		{
+			Comment: "long-line",
+			Input:   longLine + "\nend\n",
+			Options: []read.Option{
+				read.WithMaxTokenSize(len(longLine) + 1),
+			},
+			Result:  []string{longLine, "end"},
+			Offsets: []int64{0, int64(len(longLine) + 1)},
+		},
+		{
+			Comment: "invalid-max-token-size",
+			Input:   "a\n",
+			Options: []read.Option{
+				read.WithMaxTokenSize(-1),
+			},
+			IsError: true,
+		},
+		{
+			Comment: "nul",
+			Input:   "a b\x00c\x00",
+			Options: []read.Option{
+				read.WithSplit(read.ScanNUL),
+			},
+			Result:  []string{"a b", "c"},
+			Offsets: []int64{0, 4},
+		},
+		{
+			Comment: "delimiter",
+			Input:   "a<>b<>c",
+			Options: []read.Option{
+				read.WithSplit(read.ScanDelimiter([]byte("<>"))),
+			},
+			Result:  []string{"a", "b", "c"},
+			Offsets: []int64{0, 3, 6},
+		},
+		{
+			Comment: "empty-delimiter",
+			Input:   "a\nb",
+			Options: []read.Option{
+				read.WithSplit(read.ScanDelimiter(nil)),
+			},
+			IsError: true,
+		},
+		{
+			Comment: "regexp",
+			Input:   "a, b,c ,  d",
+			Options: []read.Option{
+				read.WithSplit(read.ScanRegexp(regexp.MustCompile(`\s*,\s*`))),
+			},
+			Result:  []string{"a", "b", "c", "d"},
+			Offsets: []int64{0, 3, 5, 10},
+		},
+		{
+			Comment: "regexp-empty-match",
+			Input:   "a,b,,c",
+			Options: []read.Option{
+				read.WithSplit(read.ScanRegexp(regexp.MustCompile(`,*`))),
+			},
+			Result:  []string{"a", "b", "c"},
+			Offsets: []int64{0, 2, 5},
+		},
+		{
+			Comment: "regexp-word-boundary",
+			Input:   "a,b,c",
+			Options: []read.Option{
+				read.WithSplit(read.ScanRegexp(regexp.MustCompile(`\b,*`))),
+			},
+			Result:  []string{"a", "b", "c"},
+			Offsets: []int64{0, 2, 4},
+		},
+		{
+			Comment: "paragraphs",
+			Input:   "\n\npara1\nline2\n\n\npara2\n",
+			Options: []read.Option{
+				read.WithSplit(read.ScanParagraphs),
+			},
+			Result:  []string{"para1\nline2", "para2"},
+			Offsets: []int64{2, 16},
+		},
+		{
+			Comment: "fixed-width",
+			Input:   "abcdefg",
+			Options: []read.Option{
+				read.WithSplit(read.ScanFixedWidth(3)),
+			},
+			Result:  []string{"abc", "def", "g"},
+			Offsets: []int64{0, 3, 6},
+		},
+		{
+			Comment: "invalid-width",
+			Input:   "abc",
+			Options: []read.Option{
+				read.WithSplit(read.ScanFixedWidth(0)),
+			},
+			IsError: true,
+		},
 	}
 
 	for _, tt := range testcases {
@@ -162,3 +349,69 @@ func TestStreamBuilder(t *testing.T) {
 		})
 	}
 }
+
+func TestFileSourceStream(t *testing.T) {
+	dir, err := ioutil.TempDir("", "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const content = "compressed\nlines\n"
+	for _, f := range []compress.Format{compress.None, compress.Gzip, compress.Zstd} {
+		t.Run(f.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := compress.NewWriter(&buf, f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := w.Write([]byte(content)); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if got := compress.Detect(buf.Bytes()); got != f {
+				t.Errorf("detected %v", got)
+			}
+			path := filepath.Join(dir, f.String())
+			if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+			var r []string
+			if err := functions.NewFileSourceStream(path).Map(func(x []byte) string {
+				return string(x)
+			}).As(&r); err != nil {
+				t.Fatal(err)
+			}
+			if expected := []string{"compressed", "lines"}; !cmp.Equal(r, expected) {
+				t.Errorf("  actual: %v\nexpected: %v", r, expected)
+			}
+		})
+	}
+
+	t.Run("bzh-text", func(t *testing.T) {
+		if got := compress.Detect([]byte("BZh91AY&SY")); got != compress.Bzip2 {
+			t.Errorf("detected %v", got)
+		}
+		path := filepath.Join(dir, "bzh")
+		if err := ioutil.WriteFile(path, []byte("BZhello\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		var r []string
+		if err := functions.NewFileSourceStream(path).Map(func(x []byte) string {
+			return string(x)
+		}).As(&r); err != nil {
+			t.Fatal(err)
+		}
+		if expected := []string{"BZhello"}; !cmp.Equal(r, expected) {
+			t.Errorf("  actual: %v\nexpected: %v", r, expected)
+		}
+	})
+
+	t.Run("not-found", func(t *testing.T) {
+		if err := functions.NewFileSourceStream(filepath.Join(dir, "not-found")).Err(); err == nil {
+			t.Error("should be error")
+		}
+	})
+}
@@ -0,0 +1,163 @@
+/*
+Package compress provides transparent compression and decompression
+*/
+package compress
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"tools/pkg/errors"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+//go:generate stringer -type=Format -output generated.format_string.go
+type Format int
+
+const (
+	UnknownFormat Format = iota
+	// None is not compressed
+	None
+	// Gzip is gzip
+	Gzip
+	// Bzip2 is bzip2, decompression only
+	Bzip2
+	// Zstd is zstandard
+	Zstd
+)
+
+var (
+	magicNumbers = []struct {
+		Magic  []byte
+		Format Format
+		// Valid checks bytes after magic if not nil
+		Valid func(b []byte) bool
+	}{
+		{
+			Magic:  []byte{0x1f, 0x8b},
+			Format: Gzip,
+		},
+		{
+			Magic:  []byte("BZh"),
+			Format: Bzip2,
+			// block size 1-9
+			Valid: func(b []byte) bool {
+				return len(b) > 0 && b[0] >= '1' && b[0] <= '9'
+			},
+		},
+		{
+			Magic:  []byte{0x28, 0xb5, 0x2f, 0xfd},
+			Format: Zstd,
+		},
+	}
+	maxMagicLength = 4
+
+	UnsupportedFormat = errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("unsupported format"))
+)
+
+// Detect returns format by magic bytes at the head of b
+func Detect(b []byte) Format {
+	for _, m := range magicNumbers {
+		if bytes.HasPrefix(b, m.Magic) && (m.Valid == nil || m.Valid(b[len(m.Magic):])) {
+			return m.Format
+		}
+	}
+	return None
+}
+
+// ParseFormat converts name into format, e.g. gzip, bzip2, zstd, none
+func ParseFormat(name string) (Format, errors.Error) {
+	switch name {
+	case "", "none":
+		return None, nil
+	case "gzip", "gz":
+		return Gzip, nil
+	case "bzip2", "bz2":
+		return Bzip2, nil
+	case "zstd", "zst":
+		return Zstd, nil
+	}
+	return UnknownFormat, UnsupportedFormat
+}
+
+type (
+	readCloser struct {
+		io.Reader
+		close func() error
+	}
+)
+
+func (s *readCloser) Close() error { return s.close() }
+
+// NewReader detects format of r by magic bytes and returns a reader that decompresses r.
+// returns a reader reads r as is if r is not compressed.
+// Close does not close r
+func NewReader(r io.Reader) (io.ReadCloser, errors.Error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(maxMagicLength)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, errors.NewError().SetCode(errors.IO).SetError(err)
+	}
+	return NewFormatReader(br, Detect(head))
+}
+
+// NewFormatReader returns a reader that decompresses r as format f.
+// Close does not close r
+func NewFormatReader(r io.Reader, f Format) (io.ReadCloser, errors.Error) {
+	switch f {
+	case None:
+		return ioutil.NopCloser(r), nil
+	case Gzip:
+		x, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, errors.NewError().SetCode(errors.IO).SetError(err)
+		}
+		return x, nil
+	case Bzip2:
+		return ioutil.NopCloser(bzip2.NewReader(r)), nil
+	case Zstd:
+		x, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, errors.NewError().SetCode(errors.IO).SetError(err)
+		}
+		return &readCloser{
+			Reader: x,
+			close: func() error {
+				x.Close()
+				return nil
+			},
+		}, nil
+	}
+	return nil, UnsupportedFormat
+}
+
+type (
+	nopWriteCloser struct {
+		io.Writer
+	}
+)
+
+func (*nopWriteCloser) Close() error { return nil }
+
+// NewWriter returns a writer that compresses into w as format f.
+// Close flushes compressed data but does not close w
+func NewWriter(w io.Writer, f Format) (io.WriteCloser, errors.Error) {
+	switch f {
+	case None:
+		return &nopWriteCloser{Writer: w}, nil
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	case Zstd:
+		x, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, errors.NewError().SetCode(errors.IO).SetError(err)
+		}
+		return x, nil
+	}
+	return nil, UnsupportedFormat
+}
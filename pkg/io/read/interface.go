@@ -12,7 +12,8 @@ import (
 )
 
 var (
-	EOI = errors.NewError().SetCode(errors.Normal).SetError(fmt.Errorf("end of iterator"))
+	EOI                 = errors.NewError().SetCode(errors.Normal).SetError(fmt.Errorf("end of iterator"))
+	InvalidMaxTokenSize = errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("max token size must be positive"))
 )
 
 type (
@@ -20,32 +21,142 @@ type (
 		// Next yields next one.
 		// return EOI if end of iterator
 		Next() ([]byte, errors.Error)
+		// Offset returns byte offset of the record yielded last
+		Offset() int64
 	}
+
+	// Option changes option of scanner iterator
+	Option func(*scannerIterator)
 )
 
+// WithSplit specifies split function.
+// default: bufio.ScanLines
+func WithSplit(f bufio.SplitFunc) Option {
+	return func(s *scannerIterator) {
+		s.split = f
+	}
+}
+
+// WithMaxTokenSize specifies the maximum size of a record.
+// Next returns InvalidMaxTokenSize if n is not positive.
+// default: bufio.MaxScanTokenSize
+func WithMaxTokenSize(n int) Option {
+	return func(s *scannerIterator) {
+		s.maxTokenSize = n
+	}
+}
+
 type (
 	scannerIterator struct {
-		sc *bufio.Scanner
+		sc           *bufio.Scanner
+		split        bufio.SplitFunc
+		maxTokenSize int
+		consumed     int64
+		offset       int64
+		err          errors.Error
 	}
 )
 
 // NewScannerIterator makes an iterator that yields line
-func NewScannerIterator(r io.Reader) Iterator {
-	return &scannerIterator{
-		sc: bufio.NewScanner(r),
+func NewScannerIterator(r io.Reader, options ...Option) Iterator {
+	s := &scannerIterator{
+		sc:           bufio.NewScanner(r),
+		split:        bufio.ScanLines,
+		maxTokenSize: bufio.MaxScanTokenSize,
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+	s.sc.Split(s.splitFunc)
+	if s.maxTokenSize < 1 {
+		s.err = InvalidMaxTokenSize
+		return s
+	}
+	if s.maxTokenSize != bufio.MaxScanTokenSize {
+		initSize := 4096
+		if initSize > s.maxTokenSize {
+			initSize = s.maxTokenSize
+		}
+		s.sc.Buffer(make([]byte, initSize), s.maxTokenSize)
+	}
+	return s
+}
+
+// splitFunc calls split function and tracks offset of token
+func (s *scannerIterator) splitFunc(data []byte, atEOF bool) (int, []byte, error) {
+	advance, token, err := s.split(data, atEOF)
+	if token != nil {
+		s.offset = s.consumed + int64(tokenStart(data, token))
+	}
+	s.consumed += int64(advance)
+	return advance, token, err
+}
+
+// tokenStart returns index of token in data if token is a part of data
+func tokenStart(data, token []byte) int {
+	if len(token) == 0 {
+		return 0
+	}
+	i := cap(data) - cap(token)
+	if i < 0 || i >= len(data) || &data[i] != &token[0] {
+		return 0
 	}
+	return i
 }
 
 func (s *scannerIterator) Next() ([]byte, errors.Error) {
+	if s.err != nil {
+		return nil, s.err
+	}
 	if s.sc.Scan() {
 		return s.sc.Bytes(), nil
 	}
 	if err := s.sc.Err(); err != nil {
+		var e errors.Error
+		if errors.As(err, &e) {
+			return nil, e
+		}
 		return nil, errors.NewError().SetCode(errors.System).SetError(err)
 	}
 	return nil, EOI
 }
 
+func (s *scannerIterator) Offset() int64 {
+	return s.offset
+}
+
+type (
+	// Record is a record with its position
+	Record interface {
+		Bytes() []byte
+		// Offset returns byte offset of the record from the beginning of reader
+		Offset() int64
+	}
+
+	record struct {
+		b      []byte
+		offset int64
+	}
+)
+
+func (s *record) Bytes() []byte { return s.b }
+func (s *record) Offset() int64 { return s.offset }
+
+// NextRecord yields next record with its offset.
+// bytes of record are copied so that it is not overwritten by next scan
+func NextRecord(iter Iterator) (Record, errors.Error) {
+	b, err := iter.Next()
+	if err != nil {
+		return nil, err
+	}
+	c := make([]byte, len(b))
+	copy(c, b)
+	return &record{
+		b:      c,
+		offset: iter.Offset(),
+	}, nil
+}
+
 // Read reads all as string
 func Read(r io.Reader) (string, errors.Error) {
 	var (
@@ -58,7 +169,7 @@ func Read(r io.Reader) (string, errors.Error) {
 			buf = append(buf, string(b))
 			continue
 		}
-		if err == EOI {
+		if errors.Is(err, EOI) {
 			break
 		}
 		return "", errors.NewError().SetCode(errors.System).SetError(err)
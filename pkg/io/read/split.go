@@ -0,0 +1,116 @@
+package read
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"tools/pkg/errors"
+)
+
+var (
+	InvalidWidth     = errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("width must be positive"))
+	InvalidDelimiter = errors.NewError().SetCode(errors.Validate).SetError(fmt.Errorf("delimiter must not be empty"))
+)
+
+// ScanNUL is a split function for NUL-separated records, such as `find -print0` output
+func ScanNUL(data []byte, atEOF bool) (int, []byte, error) {
+	return scanDelimiter(data, atEOF, []byte{0})
+}
+
+// ScanDelimiter returns a split function for records separated by delim.
+// delim must not be empty
+func ScanDelimiter(delim []byte) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if len(delim) == 0 {
+			return 0, nil, InvalidDelimiter
+		}
+		return scanDelimiter(data, atEOF, delim)
+	}
+}
+
+func scanDelimiter(data []byte, atEOF bool, delim []byte) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.Index(data, delim); i >= 0 {
+		return i + len(delim), data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// ScanRegexp returns a split function for records separated by matches of re.
+// empty matches are ignored.
+// re is matched against buffered data from the beginning of the current record,
+// so ^ and \A match at the beginning of every record
+func ScanRegexp(re *regexp.Regexp) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+		// find more matches only if leading ones are empty, the whole data is the context of \b
+		for n := 1; ; n *= 2 {
+			locs := re.FindAllIndex(data, n)
+			for _, loc := range locs {
+				i, j := loc[0], loc[1]
+				if i == j {
+					continue
+				}
+				// match may be extended by following data
+				if j == len(data) && !atEOF {
+					return 0, nil, nil
+				}
+				return j, data[:i], nil
+			}
+			if len(locs) < n {
+				break
+			}
+		}
+		if atEOF {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
+
+var (
+	paragraphSeparator = regexp.MustCompile(`\r?\n(\r?\n)+`)
+	scanParagraphs     = ScanRegexp(paragraphSeparator)
+)
+
+// ScanParagraphs is a split function for records separated by blank lines.
+// leading blank lines are skipped, trailing line break is removed
+func ScanParagraphs(data []byte, atEOF bool) (int, []byte, error) {
+	var skip int
+	for skip < len(data) && (data[skip] == '\n' || data[skip] == '\r') {
+		skip++
+	}
+	if skip > 0 {
+		return skip, nil, nil
+	}
+	advance, token, err := scanParagraphs(data, atEOF)
+	if token != nil {
+		token = bytes.TrimRight(token, "\r\n")
+	}
+	return advance, token, err
+}
+
+// ScanFixedWidth returns a split function for records of width bytes.
+// last record may be shorter than width
+func ScanFixedWidth(width int) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if width <= 0 {
+			return 0, nil, InvalidWidth
+		}
+		if len(data) >= width {
+			return width, data[:width], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
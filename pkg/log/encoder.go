@@ -0,0 +1,130 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const timeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+type (
+	// Entry is a log
+	Entry struct {
+		// Time is zero unless WithTime
+		Time  time.Time
+		Level Level
+		// Caller is empty unless WithCaller
+		Caller  string
+		Message string
+		Fields  []Field
+	}
+
+	// Field is a key and value pair
+	Field struct {
+		Key   string
+		Value interface{}
+	}
+
+	// Encoder writes an entry
+	Encoder interface {
+		Encode(w io.Writer, e *Entry) error
+	}
+
+	textEncoder struct{}
+	jsonEncoder struct{}
+)
+
+// newFields makes fields from key and value pairs, odd key gets value nil
+func newFields(kv []interface{}) []Field {
+	r := make([]Field, 0, (len(kv)+1)/2)
+	for i := 0; i < len(kv); i += 2 {
+		f := Field{Key: fmt.Sprint(kv[i])}
+		if i+1 < len(kv) {
+			f.Value = kv[i+1]
+		}
+		r = append(r, f)
+	}
+	return r
+}
+
+// NewTextEncoder creates an encoder writes `time L | caller | message key=value` per line
+func NewTextEncoder() Encoder {
+	return &textEncoder{}
+}
+
+func (*textEncoder) Encode(w io.Writer, e *Entry) error {
+	var b strings.Builder
+	if !e.Time.IsZero() {
+		b.WriteString(e.Time.Format(timeFormat))
+		b.WriteString(" ")
+	}
+	if x, ok := levelToLabel[e.Level]; ok {
+		fmt.Fprintf(&b, "%s | ", x)
+	}
+	if e.Caller != "" {
+		fmt.Fprintf(&b, "%s | ", e.Caller)
+	}
+	b.WriteString(e.Message)
+	for _, f := range e.Fields {
+		v := fmt.Sprint(f.Value)
+		if strings.ContainsAny(v, " \t\n\"=") {
+			v = fmt.Sprintf("%q", v)
+		}
+		fmt.Fprintf(&b, " %s=%s", f.Key, v)
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// NewJSONEncoder creates an encoder writes an object per line,
+// keys are time, level, caller, msg and fields
+func NewJSONEncoder() Encoder {
+	return &jsonEncoder{}
+}
+
+func (*jsonEncoder) Encode(w io.Writer, e *Entry) error {
+	var (
+		b    strings.Builder
+		keys = map[string]bool{}
+	)
+	var write = func(k string, v interface{}) {
+		if keys[k] {
+			return
+		}
+		keys[k] = true
+		if len(keys) > 1 {
+			b.WriteString(",")
+		}
+		kb, _ := json.Marshal(k)
+		vb, err := json.Marshal(v)
+		if err != nil {
+			vb, _ = json.Marshal(fmt.Sprint(v))
+		}
+		b.Write(kb)
+		b.WriteString(":")
+		b.Write(vb)
+	}
+	b.WriteString("{")
+	if !e.Time.IsZero() {
+		write("time", e.Time.Format(timeFormat))
+	}
+	write("level", e.Level.String())
+	if e.Caller != "" {
+		write("caller", e.Caller)
+	}
+	write("msg", e.Message)
+	for _, f := range e.Fields {
+		v := f.Value
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		write(f.Key, v)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -5,23 +5,53 @@ package log
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
 )
 
 type (
 	// Level is log level
 	Level int
+
 	// Logger writes log
-	Logger struct {
-		level Level
+	Logger interface {
+		Debug(format string, a ...interface{})
+		Info(format string, a ...interface{})
+		Warn(format string, a ...interface{})
+		Error(format string, a ...interface{})
+		// With returns a logger writes key and value pairs as fields in addition
+		With(kv ...interface{}) Logger
+	}
+
+	logger struct {
+		level  Level
+		out    *output
+		fields []Field
+		time   bool
+		caller bool
+		now    func() time.Time
+	}
+
+	// output is writer shared by loggers derived by With
+	output struct {
+		mux sync.Mutex
+		w   io.Writer
+		enc Encoder
 	}
+
 	// Option indicates logger option
-	Option func(logger *Logger)
+	Option func(logger *logger)
 )
 
 const (
 	Debug Level = iota
 	Info
+	Warn
+	Error
 	None
 )
 
@@ -29,12 +59,28 @@ var (
 	levelToLabel = map[Level]string{
 		Debug: "D",
 		Info:  "I",
+		Warn:  "W",
+		Error: "E",
+	}
+	levelToName = map[Level]string{
+		Debug: "debug",
+		Info:  "info",
+		Warn:  "warn",
+		Error: "error",
 	}
 )
 
-// NewLogger makes logger and applies options
-func NewLogger(options ...Option) *Logger {
-	x := &Logger{}
+// NewLogger makes logger and applies options.
+// default: info level, text encoder, stderr
+func NewLogger(options ...Option) Logger {
+	x := &logger{
+		level: Info,
+		out: &output{
+			w:   os.Stderr,
+			enc: NewTextEncoder(),
+		},
+		now: time.Now,
+	}
 	for _, opt := range options {
 		opt(x)
 	}
@@ -43,28 +89,118 @@ func NewLogger(options ...Option) *Logger {
 
 // WithLevel returns option that set log level
 func WithLevel(level Level) Option {
-	return Option(func(logger *Logger) {
+	return Option(func(logger *logger) {
 		logger.level = level
 	})
 }
 
-func (s *Logger) log(level Level, format string, a ...interface{}) {
+// WithWriter specifies destination of log, see NewFileWriter
+func WithWriter(w io.Writer) Option {
+	return Option(func(logger *logger) {
+		logger.out.w = w
+	})
+}
+
+// WithEncoder specifies format of log
+func WithEncoder(enc Encoder) Option {
+	return Option(func(logger *logger) {
+		logger.out.enc = enc
+	})
+}
+
+// WithTime writes timestamp
+func WithTime() Option {
+	return Option(func(logger *logger) {
+		logger.time = true
+	})
+}
+
+// WithCaller writes file and line of the caller
+func WithCaller() Option {
+	return Option(func(logger *logger) {
+		logger.caller = true
+	})
+}
+
+// WithClock specifies source of timestamp.
+// default: time.Now
+func WithClock(now func() time.Time) Option {
+	return Option(func(logger *logger) {
+		logger.now = now
+	})
+}
+
+// NewFileWriter opens file to append log, creates it if not exists
+func NewFileWriter(path string) (io.WriteCloser, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+}
+
+func (s Level) String() string {
+	if x, ok := levelToName[s]; ok {
+		return x
+	}
+	return fmt.Sprintf("Level(%d)", int(s))
+}
+
+// With returns a logger has fields in addition, overrides value of the same key
+func (s *logger) With(kv ...interface{}) Logger {
+	x := *s
+	x.fields = append([]Field{}, s.fields...)
+	for _, f := range newFields(kv) {
+		replaced := false
+		for i, y := range x.fields {
+			if y.Key == f.Key {
+				x.fields[i] = f
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			x.fields = append(x.fields, f)
+		}
+	}
+	return &x
+}
+
+// log writes an entry, depth is number of frames between the caller and log
+func (s *logger) log(level Level, depth int, format string, a ...interface{}) {
 	if s.level > level {
 		return
 	}
-	var label string
-	if x, ok := levelToLabel[level]; ok {
-		label = fmt.Sprintf("%s | ", x)
+	e := &Entry{
+		Level:   level,
+		Message: fmt.Sprintf(format, a...),
+		Fields:  s.fields,
+	}
+	if s.time {
+		e.Time = s.now()
+	}
+	if s.caller {
+		if _, file, line, ok := runtime.Caller(depth + 1); ok {
+			e.Caller = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+		}
 	}
-	_, _ = fmt.Fprintf(os.Stderr, "%s%s\n", label, fmt.Sprintf(format, a...))
+	s.out.mux.Lock()
+	defer s.out.mux.Unlock()
+	_ = s.out.enc.Encode(s.out.w, e)
+}
+
+// Debug writes debug log
+func (s *logger) Debug(format string, a ...interface{}) {
+	s.log(Debug, 1, format, a...)
 }
 
 // Info writes info log
-func (s *Logger) Info(format string, a ...interface{}) {
-	s.log(Info, format, a...)
+func (s *logger) Info(format string, a ...interface{}) {
+	s.log(Info, 1, format, a...)
 }
 
-// Debug writes debug log
-func (s *Logger) Debug(format string, a ...interface{}) {
-	s.log(Debug, format, a...)
+// Warn writes warn log
+func (s *logger) Warn(format string, a ...interface{}) {
+	s.log(Warn, 1, format, a...)
+}
+
+// Error writes error log
+func (s *logger) Error(format string, a ...interface{}) {
+	s.log(Error, 1, format, a...)
 }
@@ -0,0 +1,109 @@
+package log_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"tools/pkg/log"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLogger(t *testing.T) {
+	var (
+		now = func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) }
+	)
+	testcases := []struct {
+		Comment string
+		Options []log.Option
+		Log     func(l log.Logger)
+		Result  string
+	}{
+		{
+			Comment: "text",
+			Log: func(l log.Logger) {
+				l.Debug("hidden")
+				l.Info("hello %d", 1)
+				l.With("stage", "map", "n", 2).Warn("warn")
+				l.Error("error")
+			},
+			Result: "I | hello 1\nW | warn stage=map n=2\nE | error\n",
+		},
+		{
+			Comment: "level",
+			Options: []log.Option{log.WithLevel(log.Error)},
+			Log: func(l log.Logger) {
+				l.Info("hidden")
+				l.Warn("hidden")
+				l.Error("error")
+			},
+			Result: "E | error\n",
+		},
+		{
+			Comment: "with",
+			Log: func(l log.Logger) {
+				a := l.With("stage", "map", "msg", "a b")
+				a.With("stage", "sort").Info("x")
+				a.Info("y")
+			},
+			Result: "I | x stage=sort msg=\"a b\"\nI | y stage=map msg=\"a b\"\n",
+		},
+		{
+			Comment: "text-time-caller",
+			Options: []log.Option{log.WithTime(), log.WithCaller(), log.WithClock(now)},
+			Log: func(l log.Logger) {
+				l.Info("x")
+			},
+			Result: "2020-01-02T03:04:05.000Z I | interface_test.go:59 | x\n",
+		},
+		{
+			Comment: "json",
+			Options: []log.Option{log.WithEncoder(log.NewJSONEncoder()), log.WithTime(), log.WithClock(now)},
+			Log: func(l log.Logger) {
+				l.With("stage", "map", "msg", "field", "err", fmt.Errorf("e"), "n", 1).Warn("x")
+			},
+			Result: `{"time":"2020-01-02T03:04:05.000Z","level":"warn","msg":"x","stage":"map","err":"e","n":1}` + "\n",
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.Comment, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.Log(log.NewLogger(append([]log.Option{log.WithWriter(&buf)}, tt.Options...)...))
+			if got := buf.String(); got != tt.Result {
+				t.Errorf("  actual: %q\nexpected: %q", got, tt.Result)
+			}
+		})
+	}
+}
+
+func TestFileWriter(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	for _, msg := range []string{"first", "second"} {
+		w, err := log.NewFileWriter(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		log.NewLogger(log.WithWriter(w)).Info(msg)
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Split(string(b), "\n"); !cmp.Equal(got, []string{"I | first", "I | second", ""}) {
+		t.Errorf("not expected log: %q", got)
+	}
+}